@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagApplyFile     string
+	flagApplyPrune    bool
+	flagApplyDryRun   bool
+	flagApplyYes      bool
+	flagApplyNoPrompt bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Converge contexts, rules and repository assignments to a desired-state file",
+	Long: `Reads a desired-state file (same format as config.yaml, without tokens) and
+reconciles your contexts, path rules and repository assignments with it.
+A plan of the changes is printed first and must be confirmed (or pass --yes).
+Entries not in the file are kept unless --prune is given.
+Afterwards, you are prompted for the token of any keyring-backed context that does not have one yet.
+
+Keep a team file in a git repository and run, on each machine:
+  gham apply -f team-gham.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagApplyFile == "-" && !flagApplyYes && !flagApplyDryRun {
+			return fmt.Errorf("--yes is required when reading the desired state from stdin")
+		}
+		desired, err := config.LoadDesiredState(flagApplyFile)
+		if err != nil {
+			return err
+		}
+
+		before := config.Current().User
+		changes := config.PlanChanges(&before, desired, flagApplyPrune)
+		printPlan(changes)
+
+		if len(changes) > 0 && !flagApplyDryRun {
+			if !flagApplyYes {
+				ok, err := utils.Confirm("Apply these changes?")
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Println("Aborted; nothing was changed.")
+					return nil
+				}
+			}
+			applied, problems, err := config.Apply(desired, flagApplyPrune)
+			if len(problems) > 0 {
+				printProblems(flagApplyFile, problems)
+			}
+			if errors.Is(err, config.ErrInvalidConfig) {
+				return fmt.Errorf("not applied: the result would be an invalid configuration")
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Applied %d change(s).\n", len(applied))
+			deletePrunedTokens(&before, applied)
+		}
+
+		return ensureTokens(desired, flagApplyDryRun || flagApplyNoPrompt)
+	},
+}
+
+func printPlan(changes []config.Change) {
+	if len(changes) == 0 {
+		fmt.Println("No changes. Your configuration already matches the desired state.")
+		return
+	}
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.Action]++
+		fmt.Printf("  %s\n", c)
+	}
+	fmt.Printf("Plan: %d to add, %d to change, %d to remove.\n", counts[config.ActionAdd], counts[config.ActionChange], counts[config.ActionRemove])
+}
+
+// deletePrunedTokens removes the keyring tokens of contexts that --prune removed.
+func deletePrunedTokens(before *config.AppConfig, applied []config.Change) {
+	for _, c := range applied {
+		if c.Kind != "context" || c.Action != config.ActionRemove {
+			continue
+		}
+		if _, stillDefined := config.FindContext(c.Key); stillDefined {
+			continue // e.g. also provided by the system config
+		}
+		for _, ctx := range before.Contexts {
+			if ctx.Name == c.Key && ctx.UsesKeyring() {
+				if err := keyring.DeleteToken(c.Key); err != nil {
+					fmt.Printf("Warning: could not remove token for '%s' from keyring: %v\n", c.Key, err)
+				}
+			}
+		}
+	}
+}
+
+// ensureTokens prompts for the token of each keyring-backed desired context
+// that has none, or only lists them when listOnly is set.
+func ensureTokens(desired *config.AppConfig, listOnly bool) error {
+	for _, want := range desired.Contexts {
+		ctx, found := config.FindContext(want.Name)
+		if !found {
+			ctx = &want // dry run: not added yet
+		}
+		if !ctx.UsesKeyring() {
+			continue
+		}
+		_, err := keyring.GetToken(ctx.Name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, keyring.ErrNotFound) {
+			return fmt.Errorf("failed to check the token of context '%s': %w", ctx.Name, err)
+		}
+		if listOnly {
+			fmt.Printf("Context '%s' needs a token: run 'gham apply' without --no-prompt, or 'gham keyring gc'.\n", ctx.Name)
+			continue
+		}
+		token, err := utils.PromptForInput(fmt.Sprintf("Enter Personal Access Token (PAT) for context '%s' (will not be echoed, Enter to skip): ", ctx.Name), true)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			fmt.Printf("Skipped; context '%s' has no token yet.\n", ctx.Name)
+			continue
+		}
+		if err := keyring.StoreToken(ctx.Name, token); err != nil {
+			return fmt.Errorf("failed to store token for context '%s': %w", ctx.Name, err)
+		}
+		fmt.Printf("Stored token for context '%s'.\n", ctx.Name)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringVarP(&flagApplyFile, "file", "f", "", "Desired-state file ('-' for stdin)")
+	applyCmd.Flags().BoolVar(&flagApplyPrune, "prune", false, "Remove contexts, rules and repository assignments not in the file")
+	applyCmd.Flags().BoolVar(&flagApplyDryRun, "dry-run", false, "Only print the plan")
+	applyCmd.Flags().BoolVarP(&flagApplyYes, "yes", "y", false, "Apply without asking for confirmation")
+	applyCmd.Flags().BoolVar(&flagApplyNoPrompt, "no-prompt", false, "Do not prompt for missing tokens; only list them")
+	applyCmd.MarkFlagRequired("file")
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// annotationSkipConfigLoad marks commands that must run even when config.yaml
+// cannot be loaded (e.g. to repair it). They only get the config file path.
+const annotationSkipConfigLoad = "gham/skip-config-load"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect, validate and edit the GHAM configuration file",
+	Long:  `Provides subcommands to locate, validate, query and edit GHAM's config.yaml.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the GHAM config file in $EDITOR and validate it before saving",
+	Long: `Opens a copy of config.yaml in $VISUAL or $EDITOR. When the editor exits, the copy
+is validated; it only replaces config.yaml if it has no errors. Otherwise you can
+re-open the editor to fix them or discard your changes.`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationSkipConfigLoad: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		original, err := config.ReadRaw()
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", config.FilePath(), err)
+		}
+
+		// Edit a private copy next to the config file, so a crash never leaves a half-edited config.yaml.
+		tmp, err := os.CreateTemp(filepath.Dir(config.FilePath()), "config-edit-*.yaml")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		tmpPath := tmp.Name()
+		defer os.Remove(tmpPath)
+		_, err = tmp.Write(original)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+
+		for {
+			if err := runEditor(tmpPath); err != nil {
+				return err
+			}
+			edited, err := os.ReadFile(tmpPath)
+			if err != nil {
+				return fmt.Errorf("failed to read edited file: %w", err)
+			}
+			if bytes.Equal(edited, original) {
+				fmt.Println("No changes made.")
+				return nil
+			}
+
+			problems, err := config.ReplaceDocument(edited)
+			if len(problems) > 0 {
+				printProblems(config.FilePath(), problems)
+			}
+			if err == nil {
+				fmt.Printf("Saved %s.\n", config.FilePath())
+				return nil
+			}
+			if !errors.Is(err, config.ErrInvalidConfig) {
+				fmt.Printf("Error: %v\n", err)
+			}
+			again, promptErr := utils.Confirm("The configuration was not saved. Re-open the editor?")
+			if promptErr != nil || !again {
+				return fmt.Errorf("changes discarded; %s was left unchanged", config.FilePath())
+			}
+		}
+	},
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, run through the shell so values
+// such as "code --wait" work.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		if editor == "" {
+			editor = "notepad"
+		}
+		c = exec.Command("cmd", "/C", editor+` "`+path+`"`)
+	} else {
+		if editor == "" {
+			editor = "vi"
+		}
+		c = exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	}
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %w", editor, err)
+	}
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
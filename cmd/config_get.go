@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a value from the GHAM config file",
+	Long: `Prints the value at a dotted key. List elements are addressed by their name
+(contexts), path (repositories) or index. Examples:
+  gham config get keyring.backend
+  gham config get contexts.work.email
+  gham config get repositories.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := config.GetValue(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var flagConfigListShowOrigin bool
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the effective configuration, merged from the system and user config files",
+	Long: `Lists every effective setting as a flat key. Contexts (identity only), path rules
+and policies can come from the read-only system config (` + "`/etc/gham/config.yaml`" + `, or
+$` + config.EnvSystemConfig + `); use --show-origin to see which file each setting came from.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := config.Settings()
+		if len(settings) == 0 {
+			fmt.Println("No settings configured yet.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		for _, s := range settings {
+			if flagConfigListShowOrigin {
+				fmt.Fprintf(w, "%s:%s\t%s=%s\n", s.Layer, s.File, s.Key, s.Value)
+			} else {
+				fmt.Fprintf(w, "%s=%s\n", s.Key, s.Value)
+			}
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+
+	configListCmd.Flags().BoolVar(&flagConfigListShowOrigin, "show-origin", false, "Show the layer and file each setting came from")
+}
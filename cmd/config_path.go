@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configPathCmd = &cobra.Command{
+	Use:         "path",
+	Short:       "Print the path of the GHAM config file",
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationSkipConfigLoad: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(config.FilePath())
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPathCmd)
+}
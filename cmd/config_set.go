@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var flagConfigSetForce bool
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a value in the GHAM config file",
+	Long: `Sets the scalar value at a dotted key (see 'gham config get'), keeping comments
+and the rest of the file intact. The result is validated before it is saved.
+Examples:
+  gham config set keyring.backend file
+  gham config set contexts.work.email me@work.com`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := config.SetValue(args[0], args[1], flagConfigSetForce)
+		if len(problems) > 0 {
+			printProblems(config.FilePath(), problems)
+		}
+		if errors.Is(err, config.ErrInvalidConfig) {
+			return fmt.Errorf("not saved: the change would make the configuration invalid")
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Set %s = %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+
+	configSetCmd.Flags().BoolVar(&flagConfigSetForce, "force", false, "Allow keys that this version of gham does not know about")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the GHAM config file for mistakes",
+	Long: `Checks config.yaml (or the given file), layered over the system config, for
+duplicate context names, repository assignments and path rules pointing at
+missing contexts, repository paths that no longer exist, malformed emails,
+policy violations and invalid token source or keyring settings.
+Exits with an error if any errors (not just warnings) are found.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{annotationSkipConfigLoad: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := config.FilePath()
+		if len(args) == 1 {
+			path = args[0]
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) && len(args) == 0 {
+			fmt.Printf("%s does not exist yet; gham will create it on first use.\n", path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", path, err)
+		}
+		cfg, problems, err := config.ValidateDocument(data)
+		if err != nil {
+			return fmt.Errorf("'%s' is invalid: %w", path, err)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("%s: OK (%d context(s), %d repository assignment(s))\n", path, len(cfg.Contexts), len(cfg.Repositories))
+			return nil
+		}
+		printProblems(path, problems)
+		if config.HasErrors(problems) {
+			return fmt.Errorf("%s is invalid", path)
+		}
+		return nil
+	},
+}
+
+func printProblems(path string, problems []config.Problem) {
+	for _, p := range problems {
+		fmt.Printf("%s: %s\n", path, p)
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
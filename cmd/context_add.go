@@ -6,14 +6,24 @@ import (
 
 	"github.com/riad804/github-auth-manager/internal/config"
 	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/tokensource"
 	"github.com/riad804/github-auth-manager/internal/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	flagContextAddToken    string
-	flagContextAddEmail    string
-	flagContextAddUsername string
+	flagContextAddToken        string
+	flagContextAddEmail        string
+	flagContextAddUsername     string
+	flagContextAddTokenEnv     string
+	flagContextAddTokenCommand string
+
+	flagContextAddVaultAddr         string
+	flagContextAddVaultEngine       string
+	flagContextAddVaultPath         string
+	flagContextAddVaultField        string
+	flagContextAddVaultRoleID       string
+	flagContextAddVaultSecretIDFile string
 )
 
 var contextAddCmd = &cobra.Command{
@@ -21,6 +31,10 @@ var contextAddCmd = &cobra.Command{
 	Short: "Add a new GitHub context",
 	Long: `Adds a new GitHub context with a unique name.
 It will prompt for the Personal Access Token (PAT) if not provided via --token.
+Instead of storing the token in gham's keyring, it can be read at use time from an
+environment variable (--token-env) or from the output of a command (--token-command),
+e.g. a password manager: --token-command 'op read op://Private/GitHub/token', or from
+HashiCorp Vault (--vault-path secret/github/work, or --vault-engine github for dynamic tokens).
 Email and username for Git commits can also be provided.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -31,8 +45,14 @@ Email and username for Git commits can also be provided.`,
 
 		// Handle Token
 		token := strings.TrimSpace(flagContextAddToken)
-		var err error
-		if token == "" {
+		tokenSource, err := contextAddTokenSource(token)
+		if err != nil {
+			return err
+		}
+		if err := config.Current().Config.Policies.CheckTokenSource(&config.Context{Name: contextName, TokenSource: tokenSource}); err != nil {
+			return err
+		}
+		if tokenSource == nil && token == "" {
 			fmt.Printf("Adding context '%s'.\n", contextName)
 			token, err = utils.PromptForInput("Enter Personal Access Token (PAT) (will not be echoed): ", true)
 			if err != nil {
@@ -43,9 +63,13 @@ Email and username for Git commits can also be provided.`,
 			}
 		}
 
+		// Contexts pre-provisioned in the system config already have an identity;
+		// adding one locally only supplies its token (and any explicit overrides).
+		systemContext := config.Current().SystemContext(contextName)
+
 		// Handle Email (optional, can prompt or leave empty)
 		email := strings.TrimSpace(flagContextAddEmail)
-		if email == "" {
+		if email == "" && !systemContext {
 			promptEmail, err := utils.PromptForInput(fmt.Sprintf("Enter Git commit email for context '%s' (optional, press Enter to skip): ", contextName), false)
 			if err != nil {
 				// Non-fatal for optional fields, or make it fatal if you prefer
@@ -57,7 +81,7 @@ Email and username for Git commits can also be provided.`,
 
 		// Handle Username (optional, can prompt or leave empty for default)
 		username := strings.TrimSpace(flagContextAddUsername)
-		if username == "" {
+		if username == "" && !systemContext {
 			promptUsername, err := utils.PromptForInput(fmt.Sprintf("Enter Git commit username for context '%s' (optional, press Enter for default '%s'): ", contextName, config.DefaultUserName), false)
 			if err != nil {
 				fmt.Printf("Warning: could not read username: %v\n", err)
@@ -67,23 +91,35 @@ Email and username for Git commits can also be provided.`,
 		}
 
 		newCtx := config.Context{
-			Name:     contextName,
-			Username: username, // Will use default if empty, handled in config.AddContext
-			Email:    email,
+			Name:        contextName,
+			Username:    username, // Will use default if empty, handled in config.AddContext
+			Email:       email,
+			TokenSource: tokenSource,
 		}
 
 		if err := config.AddContext(newCtx); err != nil {
 			return fmt.Errorf("failed to add context to configuration: %w", err)
 		}
 
-		if err := keyring.StoreToken(contextName, token); err != nil {
-			// Attempt to roll back adding context from config if token storage fails
-			// Best effort, ignore error from RemoveContext here as we're already in an error state.
-			_, _ = config.RemoveContext(contextName)
-			return fmt.Errorf("failed to store token securely: %w. Context '%s' has not been fully added", err, contextName)
+		if tokenSource == nil {
+			if err := keyring.StoreToken(contextName, token); err != nil {
+				// Attempt to roll back adding context from config if token storage fails
+				// Best effort, ignore error from RemoveContext here as we're already in an error state.
+				_, _ = config.RemoveContext(contextName)
+				return fmt.Errorf("failed to store token securely: %w. Context '%s' has not been fully added", err, contextName)
+			}
 		}
 
 		fmt.Printf("Context '%s' added successfully.\n", contextName)
+		if src, err := tokensource.For(&newCtx); err == nil && tokenSource != nil {
+			fmt.Printf("Its token will be read from %s at use time.\n", src)
+		}
+		if systemContext {
+			if ctx, found := config.FindContext(contextName); found {
+				fmt.Printf("Identity from the system config: %s <%s>.\n", ctx.Username, ctx.Email)
+			}
+			return nil
+		}
 		if newCtx.Email == "" {
 			fmt.Println("Warning: No email specified for this context. Git commits might use global config email.")
 		}
@@ -94,10 +130,66 @@ Email and username for Git commits can also be provided.`,
 	},
 }
 
+// contextAddTokenSource builds the external token source requested by flags,
+// or returns nil when the token should be stored in the keyring.
+func contextAddTokenSource(token string) (*config.TokenSource, error) {
+	envName := strings.TrimSpace(flagContextAddTokenEnv)
+	command := strings.TrimSpace(flagContextAddTokenCommand)
+	vaultPath := strings.Trim(strings.TrimSpace(flagContextAddVaultPath), "/")
+	vaultEngine := strings.TrimSpace(flagContextAddVaultEngine)
+	useVault := ""
+	if vaultPath != "" || vaultEngine != "" {
+		useVault = "vault"
+	}
+	set := 0
+	for _, v := range []string{token, envName, command, useVault} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("only one of --token, --token-env, --token-command and --vault-path/--vault-engine may be given")
+	}
+	switch {
+	case envName != "":
+		return &config.TokenSource{Type: config.TokenSourceEnv, Env: envName}, nil
+	case command != "":
+		return &config.TokenSource{Type: config.TokenSourceCommand, Command: command}, nil
+	case useVault != "":
+		vault := &config.VaultSource{
+			Address: strings.TrimSpace(flagContextAddVaultAddr),
+			Engine:  vaultEngine,
+			Field:   strings.TrimSpace(flagContextAddVaultField),
+		}
+		// Like the vault CLI, the first path segment is the mount: "secret/github/work".
+		if vaultPath != "" {
+			mount, rest, _ := strings.Cut(vaultPath, "/")
+			vault.Mount, vault.Path = mount, rest
+		}
+		if roleID := strings.TrimSpace(flagContextAddVaultRoleID); roleID != "" {
+			vault.Auth = config.VaultAuth{
+				Method:       config.VaultAuthAppRole,
+				RoleID:       roleID,
+				SecretIDFile: strings.TrimSpace(flagContextAddVaultSecretIDFile),
+			}
+		}
+		return &config.TokenSource{Type: config.TokenSourceVault, Vault: vault}, nil
+	}
+	return nil, nil
+}
+
 func init() {
 	contextCmd.AddCommand(contextAddCmd)
 
 	contextAddCmd.Flags().StringVarP(&flagContextAddToken, "token", "t", "", "Personal Access Token (PAT) for the context")
+	contextAddCmd.Flags().StringVar(&flagContextAddTokenEnv, "token-env", "", "Read the token from this environment variable instead of the keyring")
+	contextAddCmd.Flags().StringVar(&flagContextAddTokenCommand, "token-command", "", "Read the token from this command's stdout instead of the keyring")
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultPath, "vault-path", "", "Read the token from Vault at <mount>/<path> (kv2) or <mount>/<endpoint> (github engine)")
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultEngine, "vault-engine", "", fmt.Sprintf("Vault secrets engine: '%s' (default) or '%s'", config.VaultEngineKV2, config.VaultEngineGitHub))
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultAddr, "vault-addr", "", "Vault address (defaults to $VAULT_ADDR)")
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultField, "vault-field", "", "kv2 secret field holding the token (defaults to 'token')")
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultRoleID, "vault-role-id", "", "Log in to Vault with this AppRole role ID instead of a Vault token")
+	contextAddCmd.Flags().StringVar(&flagContextAddVaultSecretIDFile, "vault-secret-id-file", "", "File holding the AppRole secret ID (defaults to $VAULT_SECRET_ID)")
 	contextAddCmd.Flags().StringVarP(&flagContextAddEmail, "email", "e", "", "Email for Git commits for this context")
 	contextAddCmd.Flags().StringVarP(&flagContextAddUsername, "username", "u", "", fmt.Sprintf("Username for Git commits (defaults to '%s' if not set)", config.DefaultUserName))
 }
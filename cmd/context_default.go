@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagContextDefaultHost  string
+	flagContextDefaultUnset bool
+)
+
+var contextDefaultCmd = &cobra.Command{
+	Use:   "default [name]",
+	Short: "Show or set the context used when nothing else is assigned",
+	Long: `Sets the context used for repositories that have no gham.context, assignment or
+path rule, and for 'gham git clone'. With --host, the default applies only to
+repositories whose origin remote (or clone URL) is on that host, and wins over
+the global default. Without a name, the current defaults are shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := strings.ToLower(flagContextDefaultHost)
+		if strings.ContainsAny(host, "/:") {
+			return fmt.Errorf("--host takes a host name such as 'github.example.com', not '%s'", flagContextDefaultHost)
+		}
+
+		if flagContextDefaultUnset {
+			if len(args) > 0 {
+				return fmt.Errorf("--unset takes no context name")
+			}
+			if err := config.SetDefaultContext(host, ""); err != nil {
+				return fmt.Errorf("failed to remove the default context: %w", err)
+			}
+			if host != "" {
+				fmt.Printf("Removed the default context for host '%s'.\n", host)
+			} else {
+				fmt.Println("Removed the default context.")
+			}
+			return nil
+		}
+
+		if len(args) == 0 {
+			printDefaults()
+			return nil
+		}
+
+		contextName := args[0]
+		if _, found := config.FindContext(contextName); !found {
+			return fmt.Errorf("context '%s' not found. Add it first with 'gham context add'", contextName)
+		}
+		if err := config.SetDefaultContext(host, contextName); err != nil {
+			return fmt.Errorf("failed to set the default context: %w", err)
+		}
+		if host != "" {
+			fmt.Printf("Repositories on '%s' without an assignment now use context '%s'.\n", host, contextName)
+		} else {
+			fmt.Printf("Repositories without an assignment now use context '%s'.\n", contextName)
+		}
+		return nil
+	},
+}
+
+// printDefaults lists the global and per-host defaults with their origin.
+func printDefaults() {
+	defaults := config.Current().Config.Defaults
+	if defaults.Context == "" && len(defaults.Hosts) == 0 {
+		fmt.Println("No default context is set. Repositories without an assignment use your global Git configuration.")
+		return
+	}
+	if defaults.Context != "" {
+		layer, file := config.Origin("defaults.context")
+		fmt.Printf("Default context: %s (%s config: %s)\n", defaults.Context, layer, file)
+	}
+	for _, host := range defaults.DefaultHosts() {
+		layer, file := config.Origin("defaults.hosts." + host)
+		fmt.Printf("Default for %s: %s (%s config: %s)\n", host, defaults.Hosts[host], layer, file)
+	}
+}
+
+func init() {
+	contextCmd.AddCommand(contextDefaultCmd)
+	contextDefaultCmd.Flags().StringVar(&flagContextDefaultHost, "host", "", "Set the default for repositories on this host only")
+	contextDefaultCmd.Flags().BoolVar(&flagContextDefaultUnset, "unset", false, "Remove the default instead of setting it")
+}
@@ -6,7 +6,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/riad804/github-auth-manager/internal/config"
-	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/tokensource"
 	"github.com/spf13/cobra"
 )
 
@@ -15,20 +15,27 @@ var contextListCmd = &cobra.Command{
 	Short:   "List all configured GitHub contexts",
 	Aliases: []string{"ls"},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(config.GlobalConfig.Contexts) == 0 {
+		if len(config.Current().Config.Contexts) == 0 {
 			fmt.Println("No contexts configured yet. Use 'gham context add <name>' to add one.")
 			return nil
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0) // minwidth, tabwidth, padding, padchar, flags
-		fmt.Fprintln(w, "NAME\tUSERNAME\tEMAIL\tTOKEN STORED?")
-		fmt.Fprintln(w, "----\t--------\t-----\t-------------")
+		fmt.Fprintln(w, "NAME\tUSERNAME\tEMAIL\tTOKEN SOURCE\tTOKEN AVAILABLE?")
+		fmt.Fprintln(w, "----\t--------\t-----\t------------\t----------------")
 
-		for _, ctx := range config.GlobalConfig.Contexts {
-			_, err := keyring.GetToken(ctx.Name)
-			tokenStored := "Yes"
-			if err != nil {
-				tokenStored = "No / Error" // More informative if keyring access fails
+		for i := range config.Current().Config.Contexts {
+			ctx := config.Current().Config.Contexts[i]
+			sourceName := "(invalid)"
+			tokenStored := "No / Error" // More informative if keyring access fails
+			if src, err := tokensource.For(&ctx); err == nil {
+				sourceName = src.String()
+				if ctx.TokenSourceType() == config.TokenSourceCommand {
+					// Commands may prompt (e.g. a password manager unlock), so don't run them just to list.
+					tokenStored = "(not checked)"
+				} else if _, err := src.Token(); err == nil {
+					tokenStored = "Yes"
+				}
 			}
 			username := ctx.Username
 			if username == "" {
@@ -38,7 +45,7 @@ var contextListCmd = &cobra.Command{
 			if email == "" {
 				email = "(not set)"
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ctx.Name, username, email, tokenStored)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ctx.Name, username, email, sourceName, tokenStored)
 		}
 		if err := w.Flush(); err != nil {
 			return fmt.Errorf("failed to flush output: %w", err)
@@ -17,16 +17,18 @@ var contextRemoveCmd = &cobra.Command{
 		contextName := args[0]
 
 		// Check if context exists before attempting removal
-		if _, found := config.FindContext(contextName); !found {
+		ctx, found := config.FindContext(contextName)
+		if !found {
 			return fmt.Errorf("context '%s' not found", contextName)
 		}
 
-		// First, remove from keyring
-		err := keyring.DeleteToken(contextName)
-		if err != nil {
-			// Log warning but proceed, as user might want to remove config even if keyring fails
-			fmt.Printf("Warning: could not remove token for '%s' from keyring: %v\n", contextName, err)
-			fmt.Println("Proceeding to remove context from configuration.")
+		// First, remove from keyring. Tokens from external sources are not gham's to delete.
+		if ctx.UsesKeyring() {
+			if err := keyring.DeleteToken(contextName); err != nil {
+				// Log warning but proceed, as user might want to remove config even if keyring fails
+				fmt.Printf("Warning: could not remove token for '%s' from keyring: %v\n", contextName, err)
+				fmt.Println("Proceeding to remove context from configuration.")
+			}
 		}
 
 		// Then, remove from config
@@ -35,6 +37,12 @@ var contextRemoveCmd = &cobra.Command{
 			return fmt.Errorf("failed to remove context '%s' from configuration: %w", contextName, err)
 		}
 
+		if config.Current().SystemContext(contextName) {
+			layer, file := config.Origin("contexts." + contextName)
+			fmt.Printf("Removed the token and local settings of context '%s'. The context itself is provided by the %s config '%s' and remains available.\n", contextName, layer, file)
+			return nil
+		}
+
 		if !removed { // Should not happen if FindContext found it, but as a safeguard
 			fmt.Printf("Context '%s' was not found in the configuration (this is unexpected).\n", contextName)
 			return nil
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/riad804/github-auth-manager/internal/bundle"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagExportOutput        string
+	flagExportEncrypt       bool
+	flagExportIncludeTokens bool
+	flagExportContexts      []string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export contexts, rules and repository assignments (and optionally tokens) to a bundle",
+	Long: `Writes your contexts, path rules and repository assignments to a bundle file that
+'gham import' can read on another machine. With --include-tokens, the keyring tokens
+are included too; such bundles must be encrypted (--encrypt) with a passphrase, using
+AES-256-GCM and an argon2id-derived key. The passphrase is prompted for, or read from
+$` + bundle.EnvPassphrase + `.`,
+	Example: `  gham export --encrypt --include-tokens -o gham-backup.bundle
+  gham export --context work -o work.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagExportIncludeTokens && !flagExportEncrypt {
+			return fmt.Errorf("--include-tokens requires --encrypt")
+		}
+
+		b, err := buildBundle(flagExportContexts, flagExportIncludeTokens)
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if flagExportEncrypt {
+			passphrase, err := bundlePassphrase(true)
+			if err != nil {
+				return err
+			}
+			data, err = b.Encrypt(passphrase)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt bundle: %w", err)
+			}
+		} else if data, err = b.Marshal(); err != nil {
+			return err
+		}
+
+		if flagExportOutput == "" || flagExportOutput == "-" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(flagExportOutput, data, 0600); err != nil {
+			return fmt.Errorf("failed to write bundle '%s': %w", flagExportOutput, err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d context(s), %d rule(s), %d repository assignment(s) and %d token(s) to '%s'.\n",
+			len(b.Contexts), len(b.Rules), len(b.Repositories), len(b.Tokens), flagExportOutput)
+		return nil
+	},
+}
+
+// buildBundle collects the user's own contexts (plus bare entries for system
+// contexts, so their tokens travel too), optionally limited to some contexts.
+func buildBundle(only []string, includeTokens bool) (*bundle.Bundle, error) {
+	selected := func(name string) bool {
+		if len(only) == 0 {
+			return true
+		}
+		for _, n := range only {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, name := range only {
+		if _, found := config.FindContext(name); !found {
+			return nil, fmt.Errorf("context '%s' not found", name)
+		}
+	}
+
+	store := config.Current()
+	b := bundle.New()
+	own := map[string]bool{}
+	for _, ctx := range store.User.Contexts {
+		own[ctx.Name] = true
+		if selected(ctx.Name) {
+			b.Contexts = append(b.Contexts, ctx)
+		}
+	}
+	for _, ctx := range store.Config.Contexts {
+		if !own[ctx.Name] && selected(ctx.Name) {
+			b.Contexts = append(b.Contexts, config.Context{Name: ctx.Name})
+		}
+	}
+	for _, rule := range store.User.Rules {
+		if selected(rule.ContextName) {
+			b.Rules = append(b.Rules, rule)
+		}
+	}
+	for _, repo := range store.User.Repositories {
+		if selected(repo.ContextName) {
+			b.Repositories = append(b.Repositories, repo)
+		}
+	}
+
+	if !includeTokens {
+		return b, nil
+	}
+	b.Tokens = map[string]string{}
+	for _, ctx := range b.Contexts {
+		effective, _ := config.FindContext(ctx.Name)
+		if effective == nil || !effective.UsesKeyring() {
+			continue // External sources are re-read on the new machine.
+		}
+		token, err := keyring.GetToken(ctx.Name)
+		if errors.Is(err, keyring.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Warning: context '%s' has no token in the keyring; exporting it without one.\n", ctx.Name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for context '%s': %w", ctx.Name, err)
+		}
+		b.Tokens[ctx.Name] = token
+	}
+	return b, nil
+}
+
+// bundlePassphrase reads the bundle passphrase from the environment or, with
+// confirmation when creating a bundle, from the terminal.
+func bundlePassphrase(confirm bool) (string, error) {
+	if pass := os.Getenv(bundle.EnvPassphrase); pass != "" {
+		return pass, nil
+	}
+	pass, err := utils.PromptForInput("Bundle passphrase: ", true)
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", fmt.Errorf("passphrase cannot be empty")
+	}
+	if confirm {
+		again, err := utils.PromptForInput("Repeat passphrase: ", true)
+		if err != nil {
+			return "", err
+		}
+		if again != pass {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&flagExportOutput, "output", "o", "", "Bundle file to write (default: stdout)")
+	exportCmd.Flags().BoolVar(&flagExportEncrypt, "encrypt", false, "Encrypt the bundle with a passphrase")
+	exportCmd.Flags().BoolVar(&flagExportIncludeTokens, "include-tokens", false, "Include keyring tokens (requires --encrypt)")
+	exportCmd.Flags().StringSliceVar(&flagExportContexts, "context", nil, "Only export these contexts and their rules and assignments (repeatable)")
+}
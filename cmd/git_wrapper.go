@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/riad804/github-auth-manager/internal/gitutils"
 	"github.com/spf13/cobra"
@@ -14,8 +15,12 @@ var gitCmd = &cobra.Command{
 	Long: `Wraps any git command (e.g., clone, push, pull) and automatically injects
 the appropriate GitHub credentials based on the repository's assigned GHAM context.
 For example: 'gham git clone <url>' or 'gham git push'.
-If no context is assigned to the current repository, it falls back to your system's Git configuration.`,
+If no context is assigned to the current repository, it falls back to your system's Git configuration.
+Git's global options are honored: 'gham git -C ../other push' uses the context of ../other.
+To use another context for one command, put --gham-context before the git command
+('gham git --gham-context personal fetch fork') or set GHAM_CONTEXT.`,
 	DisableFlagParsing: true, // Pass all flags directly to the underlying git command
+	Annotations:        map[string]string{annotationGhamFlagsInArgs: "true"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			// Mimic git's behavior or show GHAM-specific help for 'gham git'
@@ -27,11 +32,22 @@ If no context is assigned to the current repository, it falls back to your syste
 			return nil // Return nil to avoid Cobra printing its own usage for this specific case
 		}
 
+		args, err := consumeGhamFlags(args)
+		if err != nil {
+			return err
+		}
+		if err := setupGham(cmd); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("gham: 'git' requires a Git command")
+		}
+
 		// For debugging purposes, you might want to see what GHAM is doing.
 		// This should be behind a verbose flag in a real application.
 		// fmt.Printf("[GHAM DEBUG] Wrapping: git %s\n", strings.Join(args, " "))
 
-		err := gitutils.ExecuteGitCommandWithContext(args, os.Stdout, os.Stderr)
+		err = gitutils.ExecuteGitCommandWithContext(args, flagGhamContext, os.Stdout, os.Stderr)
 		if err != nil {
 			// The error from ExecuteGitCommandWithContext should be descriptive enough.
 			// Cobra will print it if not silenced, and main.go will os.Exit(1).
@@ -41,6 +57,41 @@ If no context is assigned to the current repository, it falls back to your syste
 	},
 }
 
+// flagGhamContext overrides the repository's context for one git command.
+var flagGhamContext string
+
+// ghamFlagsInArgs are the flags consumeGhamFlags recognizes: the persistent
+// ones and gham git's own --gham-context.
+var ghamFlagsInArgs = map[string]*string{
+	"--keyring-backend": &flagKeyringBackend,
+	"--config":          &flagConfig,
+	"--profile":         &flagProfile,
+	"--gham-context":    &flagGhamContext,
+}
+
+// consumeGhamFlags strips gham's own persistent flags from the front of args
+// and stores their values. Because gitCmd disables flag parsing, cobra hands
+// them to us verbatim (e.g. 'gham --profile client-x git push').
+func consumeGhamFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		target, ok := ghamFlagsInArgs[name]
+		if !ok {
+			return args, nil
+		}
+		if hasValue {
+			args = args[1:]
+		} else {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("flag needs an argument: %s", name)
+			}
+			value, args = args[1], args[2:]
+		}
+		*target = value
+	}
+	return args, nil
+}
+
 func init() {
 	rootCmd.AddCommand(gitCmd)
 }
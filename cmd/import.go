@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/bundle"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/importer"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagImportOnConflict string
+	flagImportFrom       string
+	flagImportYes        bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle-file> | --from <source> [path]",
+	Short: "Import contexts, rules, repository assignments and tokens from a bundle or an existing git/gh setup",
+	Long: `Imports a bundle written by 'gham export' ('-' reads stdin). Encrypted bundles ask
+for their passphrase (or read $` + bundle.EnvPassphrase + `).
+When a context, rule or repository already exists with different settings,
+--on-conflict decides what happens:
+  skip       keep the local entry (default)
+  rename     import the context as '<name>-imported'; rules and assignments keep the local entry
+  overwrite  replace the local entry (and its token)
+Token sources other than the keyring (environment variables, commands, Vault)
+are listed and must be confirmed (or accepted with --yes, required for stdin),
+and sources forbidden by policies.allowedTokenSources are refused.
+
+With --from, contexts are proposed from an existing setup instead, and each one
+is confirmed interactively (or all, with --yes):
+  gitconfig        identities from [includeIf "gitdir:..."] in ~/.gitconfig, as contexts with path rules
+                   (and from "hasconfig:remote.*.url:...", with repository assignments)
+  git-credentials  accounts and tokens from ~/.git-credentials, with assignments for repository URLs
+  gh               accounts logged in with the gh CLI (hosts.yml)
+An optional path replaces the source's default location.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if flagImportFrom != "" {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagImportFrom != "" {
+			path := ""
+			if len(args) == 1 {
+				path = args[0]
+			}
+			proposal, err := importer.Discover(flagImportFrom, path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s setup: %w", flagImportFrom, err)
+			}
+			if !flagImportYes {
+				if proposal, err = confirmProposal(proposal); err != nil {
+					return err
+				}
+			}
+			if len(proposal.Contexts) == 0 {
+				fmt.Println("Nothing to import.")
+				return nil
+			}
+			if err := importBundle(proposal); err != nil {
+				return err
+			}
+			for _, ctx := range proposal.Contexts {
+				if _, hasToken := proposal.Tokens[ctx.Name]; !hasToken && ctx.UsesKeyring() {
+					fmt.Println("Some contexts were imported without a token; run 'gham keyring gc' to enter them.")
+					break
+				}
+			}
+			return nil
+		}
+
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle '%s': %w", args[0], err)
+		}
+
+		b, err := bundle.Parse(data, func() (string, error) { return bundlePassphrase(false) })
+		if err != nil {
+			return err
+		}
+		if sources := b.ExternalTokenSources(); len(sources) > 0 && !flagImportYes {
+			if args[0] == "-" {
+				return fmt.Errorf("the bundle sets token sources outside the keyring; --yes is required to import it from stdin")
+			}
+			fmt.Println("The bundle sets these token sources outside the keyring:")
+			for _, s := range sources {
+				fmt.Printf("  %s\n", s)
+			}
+			ok, err := utils.Confirm("Import them?")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Aborted; nothing was imported.")
+				return nil
+			}
+		}
+		return importBundle(b)
+	},
+}
+
+// importBundle merges b into the config and stores its tokens.
+func importBundle(b *bundle.Bundle) error {
+	if err := b.CheckPolicies(config.Current().Config.Policies); err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+	var res *bundle.Result
+	err := config.Update(func(cfg *config.AppConfig) error {
+		var mergeErr error
+		res, mergeErr = bundle.Merge(cfg, b, flagImportOnConflict)
+		return mergeErr
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+
+	storedTokens := 0
+	for name, token := range b.Tokens {
+		localName, imported := res.ImportedName(name)
+		if !imported {
+			continue
+		}
+		ctx, found := config.FindContext(localName)
+		if !found || !ctx.UsesKeyring() {
+			continue
+		}
+		if !importTokenWanted(res, name, localName) {
+			continue
+		}
+		if err := keyring.StoreToken(localName, token); err != nil {
+			return fmt.Errorf("failed to store token for context '%s': %w", localName, err)
+		}
+		storedTokens++
+	}
+
+	printImportResult(res, storedTokens)
+	return nil
+}
+
+// importTokenWanted reports whether an imported token should be written: always
+// for new and overwritten contexts, and for unchanged ones only if they have no token yet.
+func importTokenWanted(res *bundle.Result, name, localName string) bool {
+	for _, n := range res.Unchanged {
+		if n == name {
+			_, err := keyring.GetToken(localName)
+			return err != nil
+		}
+	}
+	return true
+}
+
+func printImportResult(res *bundle.Result, storedTokens int) {
+	if len(res.Added) > 0 {
+		fmt.Printf("Added context(s): %s\n", strings.Join(res.Added, ", "))
+	}
+	for from, to := range res.Renamed {
+		fmt.Printf("Imported context '%s' as '%s' (a different '%s' already exists).\n", from, to, from)
+	}
+	if len(res.Overwritten) > 0 {
+		fmt.Printf("Overwrote context(s): %s\n", strings.Join(res.Overwritten, ", "))
+	}
+	if len(res.Unchanged) > 0 {
+		fmt.Printf("Already up to date: %s\n", strings.Join(res.Unchanged, ", "))
+	}
+	for _, s := range res.Skipped {
+		fmt.Printf("Skipped conflicting %s (use --on-conflict rename or overwrite to import it).\n", s)
+	}
+	fmt.Printf("Imported %d path rule(s), %d repository assignment(s) and %d token(s).\n", res.Rules, res.Assignments, storedTokens)
+}
+
+// confirmProposal asks about each proposed context and keeps the accepted
+// ones, with their path rules, repository assignments and tokens.
+func confirmProposal(proposal *bundle.Bundle) (*bundle.Bundle, error) {
+	accepted := bundle.New()
+	accepted.Tokens = map[string]string{}
+	for _, ctx := range proposal.Contexts {
+		fmt.Printf("\nContext '%s'\n", ctx.Name)
+		if ctx.Username != "" {
+			fmt.Printf("  Username: %s\n", ctx.Username)
+		}
+		if ctx.Email != "" {
+			fmt.Printf("  Email: %s\n", ctx.Email)
+		}
+		if _, ok := proposal.Tokens[ctx.Name]; ok {
+			fmt.Println("  Token: found (will be stored in the keyring)")
+		} else if !ctx.UsesKeyring() {
+			fmt.Printf("  Token source: %s\n", ctx.TokenSourceString())
+		}
+		var rules []config.PathRule
+		for _, r := range proposal.Rules {
+			if r.ContextName == ctx.Name {
+				rules = append(rules, r)
+				fmt.Printf("  Repositories under: %s\n", r.Path)
+			}
+		}
+		var repos []config.RepoConfig
+		for _, r := range proposal.Repositories {
+			if r.ContextName == ctx.Name {
+				repos = append(repos, r)
+				fmt.Printf("  Repository: %s\n", r.Key())
+			}
+		}
+		ok, err := utils.Confirm(fmt.Sprintf("Import context '%s'?", ctx.Name))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		accepted.Contexts = append(accepted.Contexts, ctx)
+		accepted.Rules = append(accepted.Rules, rules...)
+		accepted.Repositories = append(accepted.Repositories, repos...)
+		if token, found := proposal.Tokens[ctx.Name]; found {
+			accepted.Tokens[ctx.Name] = token
+		}
+	}
+	return accepted, nil
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&flagImportFrom, "from", "", fmt.Sprintf("Propose contexts from an existing setup (%s)", strings.Join(importer.Sources, ", ")))
+	importCmd.Flags().BoolVarP(&flagImportYes, "yes", "y", false, "Import without asking: every proposed context with --from, or a bundle's token sources")
+	importCmd.Flags().StringVar(&flagImportOnConflict, "on-conflict", bundle.OnConflictSkip, fmt.Sprintf("What to do with conflicting entries (%s)", strings.Join(bundle.ConflictStrategies, ", ")))
+}
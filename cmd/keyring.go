@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var keyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Manage the keyring where GHAM stores tokens",
+	Long:  `Provides subcommands to inspect and migrate the tokens GHAM keeps in the system keyring.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keyringCmd)
+}
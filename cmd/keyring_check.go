@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/spf13/cobra"
+)
+
+var keyringCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report keyring items and contexts that are out of sync",
+	Long: `Lists keyring items that have no matching keyring-backed context (orphans, e.g. left behind when
+'context remove' could not delete a token) and contexts whose token is missing.
+Exits with an error if any are found. Use 'gham keyring gc' to fix them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, r, err := reconcileKeyring()
+		if err != nil {
+			return err
+		}
+		if r.Clean() {
+			fmt.Printf("Keyring '%s' and configuration are in sync (%d context(s)).\n", store.Backend, len(config.Current().Config.Contexts))
+			printUnscoped(store, r)
+			return nil
+		}
+		printReconciliation(r)
+		printUnscoped(store, r)
+		return fmt.Errorf("found %d orphaned item(s) and %d context(s) without a token", len(r.Orphans), len(r.Missing))
+	},
+}
+
+// reconcileKeyring opens the selected keyring and compares it with the configured contexts.
+func reconcileKeyring() (*keyring.Store, keyring.Reconciliation, error) {
+	store, err := keyring.Default()
+	if err != nil {
+		return nil, keyring.Reconciliation{}, err
+	}
+	// Contexts with an external token source have nothing in the keyring, so any
+	// keyring item under their name is reported as an orphan.
+	names := make([]string, 0, len(config.Current().Config.Contexts))
+	for _, ctx := range config.Current().Config.Contexts {
+		if ctx.UsesKeyring() {
+			names = append(names, ctx.Name)
+		}
+	}
+	r, err := store.Reconcile(names)
+	if err != nil {
+		return nil, keyring.Reconciliation{}, fmt.Errorf("failed to reconcile keyring '%s' with configuration: %w", store.Backend, err)
+	}
+	return store, r, nil
+}
+
+// printUnscoped notes that orphans were not looked for.
+func printUnscoped(store *keyring.Store, r keyring.Reconciliation) {
+	if r.Unscoped {
+		fmt.Printf("Note: keyring '%s' also holds other applications' items, so orphaned GHAM items cannot be listed.\n", store.Backend)
+	}
+}
+
+func printReconciliation(r keyring.Reconciliation) {
+	if len(r.Orphans) > 0 {
+		fmt.Println("Orphaned keyring items (no matching context):")
+		for _, name := range r.Orphans {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if len(r.Missing) > 0 {
+		fmt.Println("Contexts without a stored token:")
+		for _, name := range r.Missing {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringCheckCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var flagKeyringGCYes bool
+
+var keyringGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete orphaned keyring items and repair contexts without a token",
+	Long: `Runs the same checks as 'gham keyring check', then offers to delete each orphaned
+keyring item, and to re-enter the token for (or remove) each context whose token is missing.
+With --yes, orphaned items are deleted without asking and contexts are left untouched.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, r, err := reconcileKeyring()
+		if err != nil {
+			return err
+		}
+		if r.Unscoped {
+			return fmt.Errorf("refusing to gc keyring '%s': it also holds other applications' items, which cannot be told apart from orphaned GHAM items. Use 'gham keyring check' to list contexts without a token", store.Backend)
+		}
+		if r.Clean() {
+			fmt.Printf("Keyring '%s' and configuration are in sync; nothing to do.\n", store.Backend)
+			return nil
+		}
+		printReconciliation(r)
+
+		var failed int
+		for _, name := range r.Orphans {
+			if !flagKeyringGCYes {
+				ok, err := utils.Confirm(fmt.Sprintf("Delete orphaned keyring item '%s'?", name))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			if err := store.DeleteToken(name); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+				failed++
+				continue
+			}
+			fmt.Printf("Deleted orphaned keyring item '%s'.\n", name)
+		}
+
+		if flagKeyringGCYes {
+			if len(r.Missing) > 0 {
+				fmt.Println("Contexts without a token were left untouched; run without --yes to repair them.")
+			}
+		} else {
+			for _, name := range r.Missing {
+				action, err := utils.PromptForInput(fmt.Sprintf("Context '%s' has no token: [r]e-enter token, [d]elete context, or [s]kip? ", name), false)
+				if err != nil {
+					return err
+				}
+				switch action {
+				case "r", "re-enter":
+					token, err := utils.PromptForInput("Enter Personal Access Token (PAT) (will not be echoed): ", true)
+					if err != nil {
+						return err
+					}
+					if token == "" {
+						fmt.Println("Empty token; skipping.")
+						continue
+					}
+					if err := store.SetToken(name, token); err != nil {
+						fmt.Printf("Warning: %v\n", err)
+						failed++
+						continue
+					}
+					fmt.Printf("Token for context '%s' stored.\n", name)
+				case "d", "delete":
+					if _, err := config.RemoveContext(name); err != nil {
+						fmt.Printf("Warning: failed to remove context '%s': %v\n", name, err)
+						failed++
+						continue
+					}
+					fmt.Printf("Context '%s' removed (repository assignments using it were unassigned).\n", name)
+				default:
+					fmt.Printf("Skipped context '%s'.\n", name)
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d item(s) could not be repaired", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringGCCmd)
+
+	keyringGCCmd.Flags().BoolVarP(&flagKeyringGCYes, "yes", "y", false, "Delete orphaned items without prompting (contexts without a token are left untouched)")
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMigrateFrom         string
+	flagMigrateTo           string
+	flagMigrateFromVersion  int
+	flagMigrateToVersion    int
+	flagMigrateDeleteSource bool
+	flagMigrateOverwrite    bool
+)
+
+var keyringMigrateCmd = &cobra.Command{
+	Use:   "migrate --from <backend> --to <backend>",
+	Short: "Copy every context token from one keyring backend to another",
+	Long: `Copies the token of every configured context from one keyring backend to another
+and reads each copy back to verify it. Source items are only deleted with --delete-source,
+and only after their copy has been verified.
+
+Backends default to the one currently selected (--keyring-backend, GHAM_KEYRING_BACKEND
+or keyring.backend in config.yaml). Use --from-version/--to-version to move tokens to a
+newer keyring item format without re-entering them.
+
+Example: gham keyring migrate --from secret-service --to file`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from := flagMigrateFrom
+		if from == "" {
+			from = keyring.SelectedBackend()
+		}
+		to := flagMigrateTo
+		if to == "" {
+			to = keyring.SelectedBackend()
+		}
+		if from == to && flagMigrateFromVersion == flagMigrateToVersion {
+			return fmt.Errorf("source and destination are the same (backend '%s', item version %d)", from, flagMigrateFromVersion)
+		}
+		if to == keyring.BackendMemory && flagMigrateDeleteSource {
+			return fmt.Errorf("refusing to delete source items when migrating to the '%s' backend, which does not persist tokens", keyring.BackendMemory)
+		}
+		if len(config.Current().Config.Contexts) == 0 {
+			fmt.Println("No contexts configured; nothing to migrate.")
+			return nil
+		}
+
+		src, err := keyring.OpenStore(from, flagMigrateFromVersion)
+		if err != nil {
+			return fmt.Errorf("failed to open source keyring '%s': %w", from, err)
+		}
+		dst, err := keyring.OpenStore(to, flagMigrateToVersion)
+		if err != nil {
+			return fmt.Errorf("failed to open destination keyring '%s': %w", to, err)
+		}
+
+		fmt.Printf("Migrating tokens from '%s' (v%d) to '%s' (v%d).\n", src.Backend, src.Version, dst.Backend, dst.Version)
+		var copied, skipped, failed int
+		opts := keyring.MigrateOptions{DeleteSource: flagMigrateDeleteSource, Overwrite: flagMigrateOverwrite}
+		for _, r := range keyring.Migrate(src, dst, config.Current().Config.Contexts, opts) {
+			switch r.Outcome {
+			case keyring.MigrateCopied:
+				fmt.Printf("  %s: %s\n", r.Context, r.Detail)
+				copied++
+			case keyring.MigrateSkipped:
+				fmt.Printf("  %s: skipped (%s)\n", r.Context, r.Detail)
+				skipped++
+			default:
+				fmt.Printf("  %s: FAILED %s\n", r.Context, r.Detail)
+				failed++
+			}
+		}
+
+		fmt.Printf("Done: %d copied, %d skipped, %d failed.\n", copied, skipped, failed)
+		if copied > 0 && dst.Backend != keyring.SelectedBackend() {
+			fmt.Printf("To use the new backend, pass --keyring-backend %s, set %s=%s, or set 'keyring.backend: %s' in %s.\n",
+				dst.Backend, keyring.EnvBackend, dst.Backend, dst.Backend, config.GetConfigFilePathForError())
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d token(s) could not be migrated", failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringMigrateCmd)
+
+	keyringMigrateCmd.Flags().StringVar(&flagMigrateFrom, "from", "", "Source keyring backend (defaults to the selected backend)")
+	keyringMigrateCmd.Flags().StringVar(&flagMigrateTo, "to", "", "Destination keyring backend (defaults to the selected backend)")
+	keyringMigrateCmd.Flags().IntVar(&flagMigrateFromVersion, "from-version", keyring.CurrentVersion, "Keyring item format version to read")
+	keyringMigrateCmd.Flags().IntVar(&flagMigrateToVersion, "to-version", keyring.CurrentVersion, "Keyring item format version to write")
+	keyringMigrateCmd.Flags().BoolVar(&flagMigrateDeleteSource, "delete-source", false, "Delete each source item after its copy is verified")
+	keyringMigrateCmd.Flags().BoolVar(&flagMigrateOverwrite, "overwrite", false, "Replace tokens that already exist in the destination with a different value")
+}
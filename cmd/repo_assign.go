@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
+	"strings"
 
 	"github.com/riad804/github-auth-manager/internal/config"
 	"github.com/riad804/github-auth-manager/internal/gitutils"
+	"github.com/riad804/github-auth-manager/internal/project"
 	"github.com/spf13/cobra"
 )
 
@@ -13,8 +14,16 @@ var repoAssignCmd = &cobra.Command{
 	Use:   "assign <context-name> [path-to-repo-root]",
 	Short: "Assign a GitHub context to a local repository",
 	Long: `Assigns a previously defined GitHub context to a local Git repository.
-The assignment is made to the root of the Git repository.
-If [path-to-repo-root] is not provided, the current directory is assumed to be within the repository.`,
+The assignment is made to the root of the Git repository. Linked worktrees share the
+assignment of their main checkout; submodules are assigned on their own.
+If [path-to-repo-root] is not provided, the current directory is assumed to be within the repository.
+
+Repositories with an origin remote are assigned by its identity (host/owner/repo), so the
+assignment survives moving or re-cloning the checkout. Use --path to assign this checkout
+only; a path assignment takes precedence over one by remote.
+With --local, the context is recorded as 'gham.context' in the repository's own git config
+instead. It travels with the checkout, is visible to other tools, and takes precedence
+over assignments in GHAM's config.`,
 	Args: cobra.RangeArgs(1, 2), // context-name is required, path is optional
 	RunE: func(cmd *cobra.Command, args []string) error {
 		contextName := args[0]
@@ -23,30 +32,91 @@ If [path-to-repo-root] is not provided, the current directory is assumed to be w
 			repoPathArg = args[1]
 		}
 
-		absPath, err := filepath.Abs(repoPathArg)
+		repo, err := lookupRepo(args[1:])
 		if err != nil {
-			return fmt.Errorf("invalid repository path argument '%s': %w", repoPathArg, err)
+			return fmt.Errorf("failed to find Git repository root at or above '%s': %w. Please provide the path to the root of a Git repository", repoPathArg, err)
 		}
+		repoRoot := repo.Root
 
-		repoRoot, err := gitutils.FindRepoRoot(absPath)
-		if err != nil {
-			return fmt.Errorf("failed to find Git repository root at or above '%s': %w. Please provide the path to the root of a Git repository", absPath, err)
+		ctx, found := config.FindContext(contextName)
+		if !found {
+			return fmt.Errorf("context '%s' does not exist. Use 'gham context list' to see available contexts", contextName)
+		}
+		if err := checkProjectContext(repo, ctx); err != nil {
+			return err
 		}
 
-		if _, found := config.FindContext(contextName); !found {
-			return fmt.Errorf("context '%s' does not exist. Use 'gham context list' to see available contexts", contextName)
+		if flagAssignLocal {
+			if err := config.SetLocalContext(repoRoot, contextName); err != nil {
+				return fmt.Errorf("failed to assign context '%s' to repository '%s': %w", contextName, repoRoot, err)
+			}
+			fmt.Printf("Context '%s' recorded as gham.context in the git config of '%s'.\n", contextName, repoRoot)
+			return nil
 		}
 
-		err = config.AssignRepoContext(repoRoot, contextName)
+		assignment := config.RepoConfig{Path: repoRoot, ContextName: contextName}
+		if !flagAssignByPath {
+			if remote := config.RemoteIdentity(repoRoot); remote != "" {
+				assignment = config.RepoConfig{Remote: remote, ContextName: contextName}
+			}
+		}
+		err = config.AssignRepoContext(repoRoot, assignment)
 		if err != nil {
 			return fmt.Errorf("failed to assign context '%s' to repository '%s': %w", contextName, repoRoot, err)
 		}
 
-		fmt.Printf("Context '%s' assigned to repository at '%s'.\n", contextName, repoRoot)
+		if assignment.Remote != "" {
+			fmt.Printf("Context '%s' assigned to repository '%s' (every checkout of it).\n", contextName, assignment.Remote)
+		} else {
+			fmt.Printf("Context '%s' assigned to repository at '%s'.\n", contextName, repoRoot)
+		}
+		if repo.Kind == gitutils.KindWorktree {
+			fmt.Println("The assignment applies to all worktrees of this repository.")
+		}
 		return nil
 	},
 }
 
+// checkProjectContext compares ctx with the repository's .gham.yaml and
+// suggests contexts that match it. Projects that refuse mismatches fail here.
+func checkProjectContext(repo *gitutils.Repo, ctx *config.Context) error {
+	req, err := project.Load(repo.WorkTree)
+	if err != nil || req == nil {
+		return err
+	}
+	violations := req.ContextViolations(ctx)
+	if len(violations) == 0 {
+		return nil
+	}
+	var matching []string
+	for _, c := range config.Current().Config.Contexts {
+		if len(req.ContextViolations(&c)) == 0 {
+			matching = append(matching, c.Name)
+		}
+	}
+	suggestion := "No configured context matches; add one with 'gham context add'."
+	if len(matching) > 0 {
+		suggestion = fmt.Sprintf("Matching contexts: %s.", strings.Join(matching, ", "))
+	}
+	var msgs []string
+	for _, v := range violations {
+		msgs = append(msgs, v.Message)
+	}
+	if req.Refuse() {
+		return fmt.Errorf("%s (required by %s). %s", strings.Join(msgs, "; "), req.Path(), suggestion)
+	}
+	fmt.Printf("Warning: %s (required by %s).\n%s\n", strings.Join(msgs, "; "), req.Path(), suggestion)
+	return nil
+}
+
+var (
+	flagAssignByPath bool
+	flagAssignLocal  bool
+)
+
 func init() {
 	repoCmd.AddCommand(repoAssignCmd)
+	repoAssignCmd.Flags().BoolVar(&flagAssignByPath, "path", false, "Assign by the checkout's path instead of its origin remote")
+	repoAssignCmd.Flags().BoolVar(&flagAssignLocal, "local", false, "Record the context as gham.context in the repository's git config")
+	repoAssignCmd.MarkFlagsMutuallyExclusive("path", "local")
 }
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/riad804/github-auth-manager/internal/config"
 	"github.com/riad804/github-auth-manager/internal/gitutils"
@@ -12,7 +15,13 @@ import (
 var repoCurrentCmd = &cobra.Command{
 	Use:   "current [path-to-repo]",
 	Short: "Show the assigned GHAM context for the current (or specified) repository",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Shows the GHAM context of the current (or specified) repository.
+
+With --explain, shows how 'gham git' arrives at it: the repository found, each
+source consulted in order, the token source and whether the token can be
+retrieved, and the settings added to git commands. --json prints the same as
+JSON.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		lookupPath := "."
 		if len(args) == 1 {
@@ -24,34 +33,189 @@ var repoCurrentCmd = &cobra.Command{
 			return fmt.Errorf("invalid path '%s': %w", lookupPath, err)
 		}
 
-		repoRoot, err := gitutils.FindRepoRoot(absPath)
+		repo, err := lookupRepo(args)
 		if err != nil {
 			// This is not an error for the command itself, just info
 			fmt.Printf("Directory '%s' is not within a Git repository tracked by GHAM or is not a Git repository.\n", absPath)
 			return nil
 		}
+		repoRoot := repo.Root
+		if flagRepoCurrentExplain || flagRepoCurrentJSON {
+			return explainRepo(repo)
+		}
 
-		contextName, found := config.GetRepoContextName(repoRoot)
-		if !found {
+		res := config.ResolveRepoContext(repoRoot)
+		if !res.Found() {
 			fmt.Printf("No GHAM context is explicitly assigned to the repository at: %s\n", repoRoot)
 			fmt.Println("Git operations will use your global or system Git configuration.")
-			return nil
+			return printProjectCheck(repo, nil)
 		}
+		contextName := res.ContextName
 
 		fmt.Printf("Repository: %s\n", repoRoot)
+		printRepoKind(repo)
 		fmt.Printf("Assigned GHAM Context: %s\n", contextName)
+		switch res.Source {
+		case config.SourceGitConfig:
+			fmt.Println("  Via gham.context in the repository's git config")
+		case config.SourcePath:
+			fmt.Printf("  Via the assignment for path '%s'\n", res.Detail)
+		case config.SourceRemote:
+			fmt.Printf("  Via remote '%s'\n", res.Detail)
+		case config.SourceRule:
+			layer, file := config.Origin("rules." + res.Detail)
+			fmt.Printf("  Via path rule '%s' (%s config: %s)\n", res.Detail, layer, file)
+		case config.SourceHostDefault:
+			fmt.Printf("  Via the default for host '%s' (nothing else is assigned)\n", res.Detail)
+		case config.SourceDefault:
+			fmt.Println("  Via the default context (nothing else is assigned)")
+		}
 
 		// Optionally, display more info about the context
-		if ctx, ctxFound := config.FindContext(contextName); ctxFound {
+		ctx, ctxFound := config.FindContext(contextName)
+		if ctxFound {
 			fmt.Printf("  Username: %s\n", ctx.Username)
 			fmt.Printf("  Email: %s\n", ctx.Email)
+			if layer, file := config.Origin("contexts." + ctx.Name); layer == config.LayerSystem {
+				fmt.Printf("  Provided by the %s config: %s\n", layer, file)
+			}
 		} else {
 			fmt.Printf("  Warning: Context '%s' is assigned but its definition was not found in GHAM configuration.\n", contextName)
 		}
-		return nil
+		return printProjectCheck(repo, ctx)
 	},
 }
 
+// sourceDescriptions describe the sources of a config.Resolution.
+var sourceDescriptions = map[string]string{
+	config.SourceOverride:    "override",
+	config.SourceGitConfig:   "gham.context in the repository's git config",
+	config.SourcePath:        "assignment by path",
+	config.SourceRemote:      "assignment by remote",
+	config.SourceRule:        "path rule",
+	config.SourceHostDefault: "default for the origin's host",
+	config.SourceDefault:     "default context",
+}
+
+// explainRepo prints how 'gham git' resolves its context in repo.
+func explainRepo(repo *gitutils.Repo) error {
+	e := gitutils.Explain(repo)
+	if flagRepoCurrentJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(e)
+	}
+
+	fmt.Printf("Repository: %s (%s)\n", e.Root, e.Kind)
+	fmt.Printf("  Git directory: %s\n", e.GitDir)
+	if e.CommonDir != e.GitDir {
+		fmt.Printf("  Common git directory: %s\n", e.CommonDir)
+	}
+	printRepoKind(repo)
+	if e.Resolution.Remote != "" {
+		fmt.Printf("  Origin: %s\n", e.Resolution.Remote)
+	}
+
+	fmt.Println("Resolution:")
+	for i, step := range e.Resolution.Steps {
+		what := sourceDescriptions[step.Source]
+		if step.Detail != "" {
+			what += " (" + step.Detail + ")"
+		}
+		result := "no match"
+		if step.Matched {
+			result = "-> " + step.ContextName
+		}
+		fmt.Printf("  %d. %s: %s\n", i+1, what, result)
+	}
+	if !e.Resolution.Found() {
+		fmt.Println("No context applies; git runs with your own configuration and credentials.")
+	}
+	if e.ContextMissing {
+		fmt.Printf("Context '%s' is not defined; git runs with your own configuration and credentials.\n", e.Resolution.ContextName)
+	}
+	if e.Refused {
+		layer, file := config.Origin("policies.requireContext")
+		fmt.Printf("'gham git' refuses to run here: the %s config '%s' sets policies.requireContext.\n", layer, file)
+	}
+	if !e.Resolution.Found() || e.ContextMissing {
+		return nil
+	}
+
+	layer, file := config.Origin("contexts." + e.Resolution.ContextName)
+	fmt.Printf("Context: %s (%s config: %s)\n", e.Resolution.ContextName, layer, file)
+	fmt.Printf("  Token source: %s\n", e.TokenSource)
+	if !e.TokenRetrieved {
+		fmt.Printf("  Token: could not be retrieved: %s\n", e.TokenError)
+		fmt.Println("  Nothing is injected; git runs with your own configuration and credentials.")
+		return nil
+	}
+	fmt.Println("  Token: retrieved")
+	fmt.Println("Injected into git commands talking to origin:")
+	for _, setting := range e.Settings {
+		fmt.Printf("  -c %s\n", setting)
+	}
+	if len(e.Settings) > 0 && strings.HasPrefix(e.Settings[0], "credential.") {
+		fmt.Printf("  The credential helper answers with username '%s' and the token.\n", e.HelperUsername)
+	} else {
+		fmt.Println("  No credential helper: origin is missing or not on a network host.")
+	}
+	fmt.Println("  Commands naming another remote get the helper for that remote's host.")
+	if e.GoGit {
+		fmt.Println("  push, pull and fetch to origin without git options such as -c run through go-git with the token instead.")
+	}
+	return nil
+}
+
+// printProjectCheck reports how ctx (nil if none) fares against the
+// repository's .gham.yaml, if it has one.
+func printProjectCheck(repo *gitutils.Repo, ctx *config.Context) error {
+	req, violations, err := gitutils.CheckProject(repo, ctx, "")
+	if err != nil || req == nil {
+		return err
+	}
+	fmt.Printf("Project requirements: %s (enforce: %s)\n", req.Path(), req.Enforce)
+	if len(violations) == 0 {
+		fmt.Println("  All met.")
+	}
+	for _, v := range violations {
+		fmt.Printf("  Not met: %s\n", v.Message)
+	}
+	if len(req.TokenScopes) > 0 {
+		fmt.Printf("  Token scopes (%s) are checked by 'gham git' on network commands.\n", strings.Join(req.TokenScopes, ", "))
+	}
+	return nil
+}
+
+// lookupRepo locates the repository at the path in args, or the one git would
+// use in the current directory (honoring GIT_DIR and GIT_WORK_TREE).
+func lookupRepo(args []string) (*gitutils.Repo, error) {
+	if len(args) > 0 {
+		return gitutils.FindRepo(args[0])
+	}
+	return gitutils.CurrentRepo()
+}
+
+// printRepoKind notes when a repository is not a plain checkout.
+func printRepoKind(repo *gitutils.Repo) {
+	switch repo.Kind {
+	case gitutils.KindWorktree:
+		fmt.Printf("  Worktree: %s (uses the main checkout's context)\n", repo.WorkTree)
+	case gitutils.KindSubmodule:
+		fmt.Println("  Submodule: resolved separately from its superproject")
+	case gitutils.KindBare:
+		fmt.Println("  Bare repository")
+	}
+}
+
+var (
+	flagRepoCurrentExplain bool
+	flagRepoCurrentJSON    bool
+)
+
 func init() {
 	repoCmd.AddCommand(repoCurrentCmd)
+	repoCurrentCmd.Flags().BoolVar(&flagRepoCurrentExplain, "explain", false, "Show how the context is resolved and what 'gham git' injects")
+	repoCurrentCmd.Flags().BoolVar(&flagRepoCurrentJSON, "json", false, "Print the explanation as JSON (implies --explain)")
 }
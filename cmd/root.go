@@ -1,16 +1,29 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
 	"github.com/spf13/cobra"
 )
 
 // Version will be set by main.go from ldflags or default
 var Version string
 
+var (
+	flagKeyringBackend string
+	flagConfig         string
+	flagProfile        string
+)
+
+// annotationGhamFlagsInArgs marks commands that disable flag parsing, so
+// gham's persistent flags reach them as arguments (see consumeGhamFlags).
+const annotationGhamFlagsInArgs = "gham/flags-in-args"
+
 var rootCmd = &cobra.Command{
 	Use:   "gham",
 	Short: "GitHub Authentication Manager (GHAM) CLI",
@@ -18,18 +31,10 @@ var rootCmd = &cobra.Command{
 of GitHub authentication contexts. It helps developers working with multiple
 GitHub accounts (personal, professional, client-based).`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Skip config initialization for 'completion' and 'version' commands
-		if cmd.Name() == "completion" || cmd.Name() == "version" || (cmd.Parent() != nil && cmd.Parent().Name() == "completion") {
-			return nil
-		}
-		if err := config.InitConfig(); err != nil {
-			// Provide a more user-friendly message if config init fails
-			fmt.Fprintf(os.Stderr, "Error initializing configuration: %v\n", err)
-			fmt.Fprintln(os.Stderr, "Please ensure your user config directory is writable.")
-			fmt.Fprintf(os.Stderr, "Attempted config path: %s\n", config.GetConfigFilePathForError()) // Helper for error
-			return err                                                                                // Return error to stop execution
+		if cmd.Annotations[annotationGhamFlagsInArgs] == "true" {
+			return nil // The command parses gham's flags itself and then calls setupGham.
 		}
-		return nil
+		return setupGham(cmd)
 	},
 	// SilenceUsage is useful for CLI tools to not show usage on every error.
 	// Errors are handled and printed, then os.Exit(1) is called.
@@ -37,6 +42,37 @@ GitHub accounts (personal, professional, client-based).`,
 	// SilenceErrors: true, // If you want to handle all error printing yourself
 }
 
+// setupGham applies the persistent flags: it pins the keyring backend, selects
+// the config file (--config, GHAM_CONFIG, --profile, GHAM_PROFILE) and loads it.
+func setupGham(cmd *cobra.Command) error {
+	// The keyring itself is opened lazily, only by commands that need a token.
+	if err := keyring.SetBackend(flagKeyringBackend); err != nil {
+		return err
+	}
+	// Skip config initialization for 'completion' and 'version' commands
+	if cmd.Name() == "completion" || cmd.Name() == "version" || (cmd.Parent() != nil && cmd.Parent().Name() == "completion") {
+		return nil
+	}
+	store, err := config.ResolveStore(flagConfig, flagProfile)
+	if err != nil {
+		return err
+	}
+	config.SetCurrent(store)
+	if cmd.Annotations[annotationSkipConfigLoad] == "true" {
+		return nil
+	}
+	if err := config.InitConfig(); err != nil {
+		// Provide a more user-friendly message if config init fails
+		fmt.Fprintf(os.Stderr, "Error initializing configuration: %v\n", err)
+		if !errors.Is(err, config.ErrConfigTooNew) {
+			fmt.Fprintln(os.Stderr, "Please ensure your user config directory is writable.")
+		}
+		fmt.Fprintf(os.Stderr, "Attempted config path: %s\n", config.GetConfigFilePathForError()) // Helper for error
+		return err                                                                                // Return error to stop execution
+	}
+	return nil
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		// Cobra prints the error by default. If SilenceErrors is true, you'd print here.
@@ -47,4 +83,7 @@ func Execute() {
 
 func init() {
 	// rootCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&flagConfig, "config", "", fmt.Sprintf("config file to use instead of the default one (or set %s)", config.EnvConfig))
+	rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", fmt.Sprintf("named profile with its own contexts, assignments and keyring namespace (or set %s)", config.EnvProfile))
+	rootCmd.PersistentFlags().StringVar(&flagKeyringBackend, "keyring-backend", "", fmt.Sprintf("keyring backend to use (%s); overrides %s and the config file", strings.Join(keyring.Backends(), ", "), keyring.EnvBackend))
 }
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/configsync"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Share contexts, rules and assignments between machines through a git repository",
+	Long: `Keeps the non-secret part of the configuration (contexts, path rules and repository
+assignments, never tokens or token commands) in a git repository that several machines push to and pull from.
+Paths under your home directory are stored as "~/..." so they carry over between machines.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+		}
+	},
+}
+
+// printSyncReport summarizes a sync for the user.
+func printSyncReport(r *configsync.Report) {
+	for _, c := range r.Conflicts {
+		fmt.Printf("Conflict: %s '%s' was changed on both sides; kept the %s version.\n", c.Kind, c.Key, c.Kept)
+	}
+	switch {
+	case r.LocalUpdated && r.Pushed:
+		fmt.Println("Local configuration updated and changes pushed.")
+	case r.LocalUpdated:
+		fmt.Println("Local configuration updated.")
+	case r.Pushed:
+		fmt.Println("Changes pushed.")
+	default:
+		fmt.Println("Already up to date.")
+	}
+	for _, name := range r.Commands {
+		fmt.Printf("Ignored the token command of context '%s' in the synced config; commands are never synced. Set one locally with 'gham config edit'.\n", name)
+	}
+	for _, name := range r.MissingToken {
+		fmt.Printf("Context '%s' has no token on this machine; run 'gham keyring gc' to enter it.\n", name)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
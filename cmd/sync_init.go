@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/configsync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagSyncContext string
+	flagSyncBranch  string
+)
+
+var syncInitCmd = &cobra.Command{
+	Use:   "init <remote>",
+	Short: "Set up syncing with a git repository",
+	Long: `Clones <remote> into GHAM's config directory and records it in the configuration.
+The remote may be empty. Use --context to authenticate with one of your contexts' tokens;
+without it, SSH remotes use your SSH agent and local paths need no credentials.
+Run 'gham sync push' afterwards to publish this machine's configuration.`,
+	Example: `  gham sync init https://github.com/me/gham-config.git --context personal
+  gham sync init git@github.com:me/gham-config.git`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagSyncContext != "" {
+			if _, found := config.FindContext(flagSyncContext); !found {
+				return fmt.Errorf("context '%s' not found", flagSyncContext)
+			}
+		}
+		if err := configsync.Init(config.Current(), args[0], flagSyncBranch, flagSyncContext, os.Stderr); err != nil {
+			return err
+		}
+		sc := config.Current().User.Sync
+		fmt.Printf("Sync set up with '%s' (branch '%s') in %s.\n", sc.Remote, sc.Branch, configsync.Dir(config.Current()))
+		fmt.Println("Run 'gham sync pull' to fetch shared configuration or 'gham sync push' to publish yours.")
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncInitCmd)
+	syncInitCmd.Flags().StringVar(&flagSyncContext, "context", "", "Context whose token authenticates to the remote")
+	syncInitCmd.Flags().StringVar(&flagSyncBranch, "branch", "", "Branch to sync (default: the remote's default branch, or 'main')")
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/configsync"
+	"github.com/spf13/cobra"
+)
+
+var syncPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Merge the shared configuration into the local one",
+	Long: `Fetches the sync repository and merges its changes into the local configuration.
+Local changes that were not pushed yet are kept, except for entries also changed remotely
+since the last sync, which take the remote version. Nothing is pushed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := configsync.Pull(config.Current(), os.Stderr)
+		if err != nil {
+			return err
+		}
+		printSyncReport(r)
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncPullCmd)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/configsync"
+	"github.com/spf13/cobra"
+)
+
+var syncPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Merge remote changes, then publish the local configuration",
+	Long: `Fetches the sync repository, merges its changes into the local configuration and pushes
+the result. Entries changed both here and remotely since the last sync keep the local version.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := configsync.Push(config.Current(), os.Stderr)
+		if err != nil {
+			return err
+		}
+		printSyncReport(r)
+		return nil
+	},
+}
+
+func init() {
+	syncCmd.AddCommand(syncPushCmd)
+}
@@ -0,0 +1,219 @@
+// Package bundle serializes gham contexts, path rules, repository assignments
+// and (optionally) tokens into a single file, encrypted with a passphrase when
+// it holds tokens, so a setup can be moved to another machine.
+package bundle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"golang.org/x/crypto/argon2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FormatName identifies gham bundles, encrypted or not.
+	FormatName = "gham-bundle"
+	// Version is the bundle format written by this build.
+	Version = 1
+	// EnvPassphrase supplies the bundle passphrase non-interactively.
+	EnvPassphrase = "GHAM_BUNDLE_PASSPHRASE"
+
+	kdfArgon2id  = "argon2id"
+	cipherAESGCM = "aes-256-gcm"
+	keyLen       = 32
+
+	// Upper bounds for KDF parameters read from a file, so a crafted bundle
+	// cannot make import allocate unbounded memory.
+	maxArgonMemoryKiB = 4 * 1024 * 1024
+	maxArgonTime      = 64
+)
+
+// ErrWrongPassphrase is returned when an encrypted bundle cannot be decrypted,
+// either because the passphrase is wrong or the file was tampered with.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// Bundle is the exported state.
+type Bundle struct {
+	Format       string              `yaml:"format"`
+	Version      int                 `yaml:"version"`
+	CreatedAt    time.Time           `yaml:"createdAt"`
+	Contexts     []config.Context    `yaml:"contexts"`
+	Rules        []config.PathRule   `yaml:"rules,omitempty"`
+	Repositories []config.RepoConfig `yaml:"repositories,omitempty"`
+	Tokens       map[string]string   `yaml:"tokens,omitempty"` // context name -> token; only in encrypted bundles
+}
+
+// New returns an empty bundle stamped with the current time.
+func New() *Bundle {
+	return &Bundle{Format: FormatName, Version: Version, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+}
+
+// envelope is the on-disk form of an encrypted bundle. The KDF parameters
+// are authenticated along with the ciphertext.
+type envelope struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	KDF        kdfParams `json:"kdf"`
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+type kdfParams struct {
+	Name      string `json:"name"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// defaultKDF follows the argon2id recommendation of RFC 9106 for memory-constrained settings.
+func defaultKDF() (kdfParams, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return kdfParams{}, err
+	}
+	return kdfParams{Name: kdfArgon2id, Salt: salt, Time: 3, MemoryKiB: 64 * 1024, Threads: 4}, nil
+}
+
+func (p kdfParams) key(passphrase string) ([]byte, error) {
+	if p.Name != kdfArgon2id {
+		return nil, fmt.Errorf("unsupported key derivation function '%s'", p.Name)
+	}
+	if p.Time == 0 || p.Time > maxArgonTime || p.MemoryKiB == 0 || p.MemoryKiB > maxArgonMemoryKiB || p.Threads == 0 || len(p.Salt) < 16 {
+		return nil, fmt.Errorf("bundle has unsafe or invalid key derivation parameters")
+	}
+	return argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.MemoryKiB, p.Threads, keyLen), nil
+}
+
+// additionalData binds the envelope header to the ciphertext.
+func (e *envelope) additionalData() ([]byte, error) {
+	header := *e
+	header.Nonce, header.Ciphertext = nil, nil
+	return json.Marshal(header)
+}
+
+// Marshal encodes b as plain YAML. Bundles carrying tokens must be encrypted.
+func (b *Bundle) Marshal() ([]byte, error) {
+	if len(b.Tokens) > 0 {
+		return nil, fmt.Errorf("a bundle with tokens must be encrypted")
+	}
+	return yaml.Marshal(b)
+}
+
+// Encrypt encodes b and encrypts it with AES-256-GCM under a key derived
+// from passphrase with argon2id.
+func (b *Bundle) Encrypt(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	plaintext, err := yaml.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	kdf, err := defaultKDF()
+	if err != nil {
+		return nil, err
+	}
+	key, err := kdf.key(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	env := envelope{Format: FormatName, Version: Version, KDF: kdf, Cipher: cipherAESGCM, Nonce: make([]byte, aead.NonceSize())}
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	ad, err := env.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, ad)
+	out, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// IsEncrypted reports whether data looks like an encrypted bundle.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// Parse decodes a bundle. passphrase is only called for encrypted bundles.
+func Parse(data []byte, passphrase func() (string, error)) (*Bundle, error) {
+	plaintext := data
+	if IsEncrypted(data) {
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, fmt.Errorf("invalid encrypted bundle: %w", err)
+		}
+		if env.Format != FormatName {
+			return nil, fmt.Errorf("not a gham bundle (format '%s')", env.Format)
+		}
+		if env.Version > Version {
+			return nil, fmt.Errorf("bundle format version %d is newer than this gham supports (%d). Please upgrade gham", env.Version, Version)
+		}
+		if env.Cipher != cipherAESGCM {
+			return nil, fmt.Errorf("unsupported bundle cipher '%s'", env.Cipher)
+		}
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, err := env.KDF.key(pass)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(env.Nonce) != aead.NonceSize() {
+			return nil, ErrWrongPassphrase
+		}
+		ad, err := env.additionalData()
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = aead.Open(nil, env.Nonce, env.Ciphertext, ad)
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+	}
+
+	var b Bundle
+	if err := yaml.Unmarshal(plaintext, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Format != FormatName {
+		return nil, fmt.Errorf("not a gham bundle")
+	}
+	if b.Version > Version {
+		return nil, fmt.Errorf("bundle format version %d is newer than this gham supports (%d). Please upgrade gham", b.Version, Version)
+	}
+	if len(b.Tokens) > 0 && !IsEncrypted(data) {
+		return nil, fmt.Errorf("refusing a bundle that carries tokens without encryption")
+	}
+	return &b, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
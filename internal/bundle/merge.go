@@ -0,0 +1,169 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// Conflict strategies for Merge, used when the bundle and the local config
+// disagree about a context name, rule path or repository path.
+const (
+	OnConflictSkip      = "skip"      // keep the local entry
+	OnConflictRename    = "rename"    // import the context under a new name; rules and assignments keep the local entry
+	OnConflictOverwrite = "overwrite" // replace the local entry
+)
+
+// ConflictStrategies lists the accepted strategy names.
+var ConflictStrategies = []string{OnConflictSkip, OnConflictRename, OnConflictOverwrite}
+
+// Result describes what Merge did.
+type Result struct {
+	Added       []string          // contexts added under their own name
+	Renamed     map[string]string // bundle context name -> name it was imported as
+	Overwritten []string          // local contexts replaced by the bundle's
+	Unchanged   []string          // contexts identical in both
+	Skipped     []string          // contexts, rules and repositories kept as they were locally
+	Rules       int               // path rules added or replaced
+	Assignments int               // repository assignments added or replaced
+}
+
+// ImportedName returns the local name a bundle context ended up under, and
+// false if it was skipped.
+func (r *Result) ImportedName(name string) (string, bool) {
+	if newName, ok := r.Renamed[name]; ok {
+		return newName, true
+	}
+	for _, list := range [][]string{r.Added, r.Overwritten, r.Unchanged} {
+		for _, n := range list {
+			if n == name {
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
+
+// CheckPolicies returns an error naming every bundle context whose token
+// source p forbids.
+func (b *Bundle) CheckPolicies(p config.Policies) error {
+	var errs []error
+	for i := range b.Contexts {
+		if err := p.CheckTokenSource(&b.Contexts[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ExternalTokenSources lists, as "name: source", the bundle's contexts whose
+// token is not read from the keyring. Importing one makes gham read that
+// variable, run that command or log in to that Vault server.
+func (b *Bundle) ExternalTokenSources() []string {
+	var sources []string
+	for _, ctx := range b.Contexts {
+		if !ctx.UsesKeyring() {
+			sources = append(sources, fmt.Sprintf("%s: %s", ctx.Name, ctx.TokenSourceString()))
+		}
+	}
+	return sources
+}
+
+// Merge imports the bundle's contexts, rules and repository assignments into cfg.
+func Merge(cfg *config.AppConfig, b *Bundle, strategy string) (*Result, error) {
+	switch strategy {
+	case OnConflictSkip, OnConflictRename, OnConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy '%s' (use %v)", strategy, ConflictStrategies)
+	}
+	res := &Result{Renamed: map[string]string{}}
+
+	exists := func(name string) bool {
+		for _, c := range cfg.Contexts {
+			if c.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, incoming := range b.Contexts {
+		i := -1
+		for j := range cfg.Contexts {
+			if cfg.Contexts[j].Name == incoming.Name {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			cfg.Contexts = append(cfg.Contexts, incoming)
+			res.Added = append(res.Added, incoming.Name)
+		case reflect.DeepEqual(cfg.Contexts[i], incoming):
+			res.Unchanged = append(res.Unchanged, incoming.Name)
+		case strategy == OnConflictOverwrite:
+			cfg.Contexts[i] = incoming
+			res.Overwritten = append(res.Overwritten, incoming.Name)
+		case strategy == OnConflictRename:
+			newName := incoming.Name + "-imported"
+			for n := 2; exists(newName); n++ {
+				newName = fmt.Sprintf("%s-imported-%d", incoming.Name, n)
+			}
+			renamed := incoming
+			renamed.Name = newName
+			cfg.Contexts = append(cfg.Contexts, renamed)
+			res.Renamed[incoming.Name] = newName
+		default:
+			res.Skipped = append(res.Skipped, "context "+incoming.Name)
+		}
+	}
+
+	// Point imported rules and assignments at the names contexts were imported as.
+	contextFor := func(name string) string {
+		if newName, ok := res.Renamed[name]; ok {
+			return newName
+		}
+		return name
+	}
+	for _, incoming := range b.Rules {
+		incoming.ContextName = contextFor(incoming.ContextName)
+		i := -1
+		for j := range cfg.Rules {
+			if cfg.Rules[j].Path == incoming.Path {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			cfg.Rules = append(cfg.Rules, incoming)
+			res.Rules++
+		case cfg.Rules[i] == incoming:
+		case strategy == OnConflictOverwrite:
+			cfg.Rules[i] = incoming
+			res.Rules++
+		default:
+			res.Skipped = append(res.Skipped, "rule "+incoming.Path)
+		}
+	}
+	for _, incoming := range b.Repositories {
+		incoming.ContextName = contextFor(incoming.ContextName)
+		i := -1
+		for j := range cfg.Repositories {
+			if cfg.Repositories[j].Key() == incoming.Key() {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			cfg.Repositories = append(cfg.Repositories, incoming)
+			res.Assignments++
+		case cfg.Repositories[i] == incoming:
+		case strategy == OnConflictOverwrite:
+			cfg.Repositories[i] = incoming
+			res.Assignments++
+		default:
+			res.Skipped = append(res.Skipped, "repository "+incoming.Key())
+		}
+	}
+	return res, nil
+}
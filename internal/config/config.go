@@ -1,12 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,26 +15,160 @@ const (
 	ConfigFileName  = "config.yaml"
 	KeyringService  = "GHAM_PAT_Storage_v1" // Consider versioning if format changes
 	DefaultUserName = "GHAM User"
+
+	backupFileSuffix = ".bak"
+)
+
+// Token source types. A context without a TokenSource uses the keyring.
+const (
+	TokenSourceKeyring = "keyring"
+	TokenSourceEnv     = "env"
+	TokenSourceCommand = "command"
+	TokenSourceVault   = "vault"
+)
+
+// TokenSource declares where a context's token comes from instead of the keyring,
+// e.g. an environment variable or a password manager command such as 'op read ...'.
+type TokenSource struct {
+	Type    string       `yaml:"type"`
+	Env     string       `yaml:"env,omitempty"`     // TokenSourceEnv: variable name
+	Command string       `yaml:"command,omitempty"` // TokenSourceCommand: run via the shell, stdout is the token
+	Vault   *VaultSource `yaml:"vault,omitempty"`   // TokenSourceVault
+}
+
+// Vault secrets engines usable as a token source.
+const (
+	VaultEngineKV2    = "kv2"
+	VaultEngineGitHub = "github"
+)
+
+// VaultSource reads a context's token from HashiCorp Vault, either a static
+// token from a KV v2 secret or a dynamic one from the GitHub secrets engine.
+type VaultSource struct {
+	Address   string    `yaml:"address,omitempty"`   // defaults to $VAULT_ADDR
+	Namespace string    `yaml:"namespace,omitempty"` // Vault Enterprise namespace, defaults to $VAULT_NAMESPACE
+	Engine    string    `yaml:"engine,omitempty"`    // "kv2" (default) or "github"
+	Mount     string    `yaml:"mount,omitempty"`     // defaults to "secret" (kv2) or "github"
+	Path      string    `yaml:"path,omitempty"`      // kv2: secret path; github: endpoint, defaults to "token"
+	Field     string    `yaml:"field,omitempty"`     // kv2: key holding the token, defaults to "token"
+	Auth      VaultAuth `yaml:"auth,omitempty"`
+}
+
+// String describes the secret, e.g. "github/work" or
+// "github/token (engine github, address https://vault.example.com)".
+func (v VaultSource) String() string {
+	path := strings.Trim(v.Path, "/")
+	if v.Mount != "" {
+		path = v.Mount + "/" + path
+	}
+	var extra []string
+	for _, f := range [][2]string{
+		{"engine", v.Engine}, {"field", v.Field}, {"address", v.Address}, {"namespace", v.Namespace},
+		{"auth", v.Auth.Method}, {"role", v.Auth.RoleID},
+	} {
+		if f[1] != "" {
+			extra = append(extra, f[0]+" "+f[1])
+		}
+	}
+	if len(extra) == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s (%s)", path, strings.Join(extra, ", "))
+}
+
+// Vault auth methods.
+const (
+	VaultAuthToken   = "token"
+	VaultAuthAppRole = "approle"
 )
 
+// VaultAuth configures how gham logs in to Vault.
+type VaultAuth struct {
+	Method       string `yaml:"method,omitempty"`       // "token" (default) or "approle"
+	TokenFile    string `yaml:"tokenFile,omitempty"`    // token: defaults to $VAULT_TOKEN, then ~/.vault-token
+	Mount        string `yaml:"mount,omitempty"`        // approle: auth mount, defaults to "approle"
+	RoleID       string `yaml:"roleId,omitempty"`       // approle
+	SecretIDFile string `yaml:"secretIdFile,omitempty"` // approle: defaults to $VAULT_SECRET_ID
+}
+
 type Context struct {
-	Name     string `yaml:"name"`
-	Username string `yaml:"username,omitempty"` // omitempty to not write if default
-	Email    string `yaml:"email,omitempty"`
+	Name        string       `yaml:"name"`
+	Username    string       `yaml:"username,omitempty"` // omitempty to not write if default
+	Email       string       `yaml:"email,omitempty"`
+	TokenSource *TokenSource `yaml:"tokenSource,omitempty"` // nil means the token is in the keyring
+}
+
+// TokenSourceType returns the context's token source type, defaulting to the keyring.
+func (c Context) TokenSourceType() string {
+	if c.TokenSource == nil || c.TokenSource.Type == "" {
+		return TokenSourceKeyring
+	}
+	return c.TokenSource.Type
+}
+
+// TokenSourceString describes the context's token source in full: the command
+// it runs, the variable it reads or the Vault secret, e.g. "env:GH_TOKEN".
+func (c Context) TokenSourceString() string {
+	ts := c.TokenSource
+	switch c.TokenSourceType() {
+	case TokenSourceEnv:
+		return "env:" + ts.Env
+	case TokenSourceCommand:
+		return "command:" + ts.Command
+	case TokenSourceVault:
+		if ts.Vault == nil {
+			return TokenSourceVault
+		}
+		return "vault:" + ts.Vault.String()
+	}
+	return c.TokenSourceType()
+}
+
+// UsesKeyring reports whether the context's token is stored in gham's keyring.
+func (c Context) UsesKeyring() bool {
+	return c.TokenSourceType() == TokenSourceKeyring
 }
 
+// RepoConfig assigns a context to a repository, either by its origin remote
+// ("host/owner/repo", so every checkout of it) or by the path of a single
+// checkout, which takes precedence.
 type RepoConfig struct {
-	Path        string `yaml:"path"`
+	Path        string `yaml:"path,omitempty"`
+	Remote      string `yaml:"remote,omitempty"`
 	ContextName string `yaml:"contextName"`
 }
 
-type AppConfig struct {
-	Contexts     []Context    `yaml:"contexts"`
-	Repositories []RepoConfig `yaml:"repositories"`
+// Key identifies the assignment: its path, or its remote if it has no path.
+func (rc RepoConfig) Key() string {
+	if rc.Path != "" {
+		return rc.Path
+	}
+	return rc.Remote
+}
+
+// KeyringConfig pins the keyring backend and bounds how long a backend call may take.
+type KeyringConfig struct {
+	Backend string `yaml:"backend,omitempty"` // e.g. "secret-service", "file"; empty means auto-detect
+	Timeout string `yaml:"timeout,omitempty"` // Go duration, e.g. "10s"
+}
+
+// SyncConfig is where 'gham sync' keeps the shared, non-secret config.
+type SyncConfig struct {
+	Remote  string `yaml:"remote"`
+	Branch  string `yaml:"branch,omitempty"`  // defaults to the remote's default branch
+	Context string `yaml:"context,omitempty"` // context whose token authenticates to the remote
 }
 
-var GlobalConfig AppConfig
-var configFilePath string // Store the actual path for error reporting
+type AppConfig struct {
+	Version      int           `yaml:"version"` // schema version, see CurrentConfigVersion
+	Contexts     []Context     `yaml:"contexts"`
+	Repositories []RepoConfig  `yaml:"repositories"`
+	Rules        []PathRule    `yaml:"rules,omitempty"`
+	Policies     Policies      `yaml:"policies,omitempty"`
+	Keyring      KeyringConfig `yaml:"keyring,omitempty"`
+	Defaults     Defaults      `yaml:"defaults,omitempty"`
+	Sync         *SyncConfig   `yaml:"sync,omitempty"`
+}
 
 func GetConfigDir() (string, error) {
 	userConfigDir, err := os.UserConfigDir()
@@ -46,10 +180,10 @@ func GetConfigDir() (string, error) {
 
 // GetConfigFilePathForError returns the path for error messages if InitConfig fails early.
 func GetConfigFilePathForError() string {
-	if configFilePath != "" {
-		return configFilePath
+	if current.Path != "" {
+		return current.Path
 	}
-	// Attempt to construct it if not yet set (e.g., MkdirAll failed)
+	// Attempt to construct it if not yet resolved (e.g., GetConfigDir failed)
 	dir, err := GetConfigDir()
 	if err != nil {
 		return "unknown (could not determine user config directory)"
@@ -57,154 +191,291 @@ func GetConfigFilePathForError() string {
 	return filepath.Join(dir, ConfigFileName)
 }
 
+// InitConfig loads the current store, resolving the default config file if
+// none was selected, and creates or upgrades the file as needed.
 func InitConfig() error {
-	configDir, err := GetConfigDir()
-	if err != nil {
+	if err := InitPath(); err != nil {
 		return err
 	}
+	return current.Load()
+}
 
+// Load reads the store's config file into s.Config, creating the file (and
+// its directory) if missing and persisting any schema upgrade.
+func (s *Store) Load() error {
+	configDir := filepath.Dir(s.Path)
 	if err := os.MkdirAll(configDir, 0750); err != nil { // 0750: rwx for user, rx for group
 		return fmt.Errorf("failed to create config directory '%s': %w", configDir, err)
 	}
 
-	configFilePath = filepath.Join(configDir, ConfigFileName)
+	if err := s.loadSystem(); err != nil {
+		return err
+	}
+	file, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	if file.raw == nil {
+		// Config file not found; create it with empty structure
+		fmt.Printf("Config file not found at %s. Creating a new one.\n", s.Path)
+		return s.Update(func(cfg *AppConfig) error { return nil }) // Ensure new file is written
+	}
+	if file.fromVersion < CurrentConfigVersion {
+		// Persist the upgrade (under the lock, with a backup) so it only happens once.
+		return s.Update(func(cfg *AppConfig) error { return nil })
+	}
+	s.setUser(file.cfg)
+	return nil
+}
 
-	viper.SetConfigName(strings.TrimSuffix(ConfigFileName, filepath.Ext(ConfigFileName)))
-	viper.SetConfigType(filepath.Ext(ConfigFileName)[1:])
-	viper.AddConfigPath(configDir)
+// configFile is config.yaml as read from disk.
+type configFile struct {
+	cfg         AppConfig
+	raw         []byte // bytes on disk; nil if the file does not exist
+	doc         []byte // document edited on save: raw, upgraded to the current schema
+	fromVersion int    // schema version found on disk
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; create it with empty structure
-			fmt.Printf("Config file not found at %s. Creating a new one.\n", configFilePath)
-			GlobalConfig = AppConfig{
-				Contexts:     []Context{},
-				Repositories: []RepoConfig{},
-			}
-			return SaveConfig() // Ensure new file is written
-		}
-		return fmt.Errorf("failed to read config file '%s': %w", configFilePath, err)
+// readFile reads the config file and upgrades it in memory to the current
+// schema. Saves replace the file atomically, so a reader never sees a partial
+// write and needs no lock.
+func (s *Store) readFile() (*configFile, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return &configFile{
+			cfg:         AppConfig{Version: CurrentConfigVersion, Contexts: []Context{}, Repositories: []RepoConfig{}},
+			fromVersion: CurrentConfigVersion,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file '%s': %w", s.Path, err)
 	}
 
-	if err := viper.Unmarshal(&GlobalConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal config from '%s': %w", configFilePath, err)
+	doc, fromVersion, err := migrateDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file '%s': %w", s.Path, err)
 	}
-	return nil
+	file := &configFile{raw: data, doc: doc, fromVersion: fromVersion}
+	if err := yaml.Unmarshal(doc, &file.cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config from '%s': %w", s.Path, err)
+	}
+	return file, nil
+}
+
+// Update runs a locked read-modify-write cycle on the current store. See Store.Update.
+func Update(fn func(cfg *AppConfig) error) error {
+	return current.Update(fn)
 }
 
-func SaveConfig() error {
-	if configFilePath == "" {
-		// This case should ideally be prevented by InitConfig always setting it
-		return fmt.Errorf("config file path not initialized. Call InitConfig first")
+// Update runs a locked read-modify-write cycle: it takes the config lock,
+// re-reads the file so changes saved by concurrent gham processes are not
+// lost, applies fn, and saves the result. fn sees only the user's own file,
+// never the system layer. s.Config reflects the saved file.
+func (s *Store) Update(fn func(cfg *AppConfig) error) error {
+	if err := s.loadSystem(); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := s.readFile()
+	if err != nil {
+		return err
+	}
+	cfg := file.cfg
+	if err := fn(&cfg); err != nil {
+		return err
+	}
+	if err := s.writeFile(file, &cfg); err != nil {
+		return err
 	}
+	s.setUser(cfg)
+	return nil
+}
 
-	configDir := filepath.Dir(configFilePath)
+// writeFile atomically replaces the config file: the new content is written
+// to a temp file in the same directory, fsynced, and renamed over the old file,
+// whose previous version is kept as config.yaml.bak. Callers hold the lock.
+// Comments, key order and unknown keys in the existing file are preserved.
+// A file upgraded from an older schema is also kept as config.yaml.v<N>.bak.
+func (s *Store) writeFile(file *configFile, cfg *AppConfig) error {
+	configDir := filepath.Dir(s.Path)
 	if err := os.MkdirAll(configDir, 0750); err != nil {
 		return fmt.Errorf("failed to ensure config directory '%s' exists for saving: %w", configDir, err)
 	}
 
-	yamlData, err := yaml.Marshal(&GlobalConfig)
+	// Edit the existing document rather than re-marshalling from scratch, so
+	// hand-written comments, key order and unknown keys are kept.
+	cfg.Version = CurrentConfigVersion
+	yamlData, err := marshalPreserving(cfg, file.doc)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config to YAML: %w", err)
 	}
+	if bytes.Equal(yamlData, file.raw) {
+		return nil // Nothing changed; keep the file and its backup as they are.
+	}
 
-	// Write with 0600 permissions (rw for user only) as it might contain sensitive paths
-	if err = os.WriteFile(configFilePath, yamlData, 0600); err != nil {
-		return fmt.Errorf("failed to write config file '%s': %w", configFilePath, err)
+	if file.raw != nil && file.fromVersion < CurrentConfigVersion {
+		versionBackup := fmt.Sprintf("%s.v%d%s", s.Path, file.fromVersion, backupFileSuffix)
+		if err := writeFileAtomic(versionBackup, file.raw); err != nil {
+			return fmt.Errorf("failed to back up config file '%s' before upgrading it: %w", s.Path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Upgraded config file '%s' from schema version %d to %d (previous version saved as '%s').\n", s.Path, file.fromVersion, CurrentConfigVersion, versionBackup)
+	}
+	if file.raw != nil {
+		if err := writeFileAtomic(s.Path+backupFileSuffix, file.raw); err != nil {
+			return fmt.Errorf("failed to back up config file '%s': %w", s.Path, err)
+		}
+	}
+	if err := writeFileAtomic(s.Path, yamlData); err != nil {
+		return fmt.Errorf("failed to write config file '%s': %w", s.Path, err)
 	}
-	// fmt.Printf("DEBUG: Configuration saved to %s\n", configFilePath)
 	return nil
 }
 
+// writeFileAtomic writes data to a temp file next to path, fsyncs it and renames
+// it into place. Files are 0600 (rw for user only) as they might contain sensitive paths.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once renamed.
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
 func FindContext(name string) (*Context, bool) {
-	for i, ctx := range GlobalConfig.Contexts {
+	return current.Config.findContext(name)
+}
+
+func (cfg *AppConfig) findContext(name string) (*Context, bool) {
+	for i, ctx := range cfg.Contexts {
 		if ctx.Name == name {
-			return &GlobalConfig.Contexts[i], true
+			return &cfg.Contexts[i], true
 		}
 	}
 	return nil, false
 }
 
 func AddContext(newCtx Context) error {
-	if _, found := FindContext(newCtx.Name); found {
-		return fmt.Errorf("context with name '%s' already exists", newCtx.Name)
-	}
-	if newCtx.Username == "" { // Apply default if username is empty
+	return current.AddContext(newCtx)
+}
+
+func (s *Store) AddContext(newCtx Context) error {
+	if newCtx.Username == "" && !s.SystemContext(newCtx.Name) { // Apply default if username is empty; system contexts bring their own
 		newCtx.Username = DefaultUserName
 	}
-	GlobalConfig.Contexts = append(GlobalConfig.Contexts, newCtx)
-	return SaveConfig()
+	if err := s.Config.Policies.CheckTokenSource(&newCtx); err != nil {
+		return err
+	}
+	return s.Update(func(cfg *AppConfig) error {
+		if _, found := cfg.findContext(newCtx.Name); found {
+			return fmt.Errorf("context with name '%s' already exists", newCtx.Name)
+		}
+		cfg.Contexts = append(cfg.Contexts, newCtx)
+		return nil
+	})
 }
 
 func RemoveContext(name string) (bool, error) {
+	return current.RemoveContext(name)
+}
+
+func (s *Store) RemoveContext(name string) (bool, error) {
 	found := false
-	var updatedContexts []Context
-	for _, ctx := range GlobalConfig.Contexts {
-		if ctx.Name == name {
-			found = true
-		} else {
-			updatedContexts = append(updatedContexts, ctx)
+	err := s.Update(func(cfg *AppConfig) error {
+		var updatedContexts []Context
+		for _, ctx := range cfg.Contexts {
+			if ctx.Name == name {
+				found = true
+			} else {
+				updatedContexts = append(updatedContexts, ctx)
+			}
+		}
+		if !found {
+			return nil // Not found, no error, but signal not found
+		}
+		cfg.Contexts = updatedContexts
+		if s.SystemContext(name) {
+			return nil // Only local overrides are removed; the context itself stays, and so do its assignments.
 		}
-	}
 
-	if !found {
-		return false, nil // Not found, no error, but signal not found
-	}
-	GlobalConfig.Contexts = updatedContexts
+		// Also remove repository assignments using this context
+		var updatedRepos []RepoConfig
+		for _, repoCfg := range cfg.Repositories {
+			if repoCfg.ContextName != name {
+				updatedRepos = append(updatedRepos, repoCfg)
+			}
+		}
+		cfg.Repositories = updatedRepos
 
-	// Also remove repository assignments using this context
-	var updatedRepos []RepoConfig
-	repoAssignmentRemoved := false
-	for _, repoCfg := range GlobalConfig.Repositories {
-		if repoCfg.ContextName != name {
-			updatedRepos = append(updatedRepos, repoCfg)
-		} else {
-			repoAssignmentRemoved = true
+		// And defaults naming it.
+		if cfg.Defaults.Context == name {
+			cfg.Defaults.Context = ""
 		}
-	}
-	if repoAssignmentRemoved {
-		GlobalConfig.Repositories = updatedRepos
-	}
+		for host, ctxName := range cfg.Defaults.Hosts {
+			if ctxName == name {
+				delete(cfg.Defaults.Hosts, host)
+			}
+		}
+		return nil
+	})
+	return found, err
+}
 
-	return true, SaveConfig()
+func AssignRepoContext(repoPath string, assignment RepoConfig) error {
+	return current.AssignRepoContext(repoPath, assignment)
 }
 
-func AssignRepoContext(repoPath, contextName string) error {
+// AssignRepoContext assigns the repository at repoPath by path or by remote.
+// It replaces any existing assignment with the same key, and one for repoPath's
+// path, which would otherwise take precedence.
+func (s *Store) AssignRepoContext(repoPath string, assignment RepoConfig) error {
 	// Path should already be absolute and validated as repo root by caller
-	// Remove existing assignment for this path, if any
-	var updatedRepos []RepoConfig
-	for _, rc := range GlobalConfig.Repositories {
-		if rc.Path == repoPath {
-			if rc.ContextName == contextName { // Already assigned to the same context
-				return nil // No change needed
+	return s.Update(func(cfg *AppConfig) error {
+		var newRepoList []RepoConfig
+		for _, rc := range cfg.Repositories {
+			if rc.Key() != assignment.Key() && rc.Path != repoPath {
+				newRepoList = append(newRepoList, rc)
 			}
-			// If different context, it will be overwritten by adding the new one below
-			// and not adding this old one to updatedRepos.
-		} else {
-			updatedRepos = append(updatedRepos, rc)
 		}
-	}
-	// If it was found and different, it's excluded. If not found, this adds it.
-	// If found and same, we returned nil above.
-	// This logic simplifies to just re-adding/replacing:
-	var newRepoList []RepoConfig
-	for _, rc := range GlobalConfig.Repositories {
-		if rc.Path != repoPath {
-			newRepoList = append(newRepoList, rc)
-		}
-	}
-	newRepoList = append(newRepoList, RepoConfig{Path: repoPath, ContextName: contextName})
-	GlobalConfig.Repositories = newRepoList
-
-	return SaveConfig()
+		newRepoList = append(newRepoList, assignment)
+		cfg.Repositories = newRepoList
+		return nil
+	})
 }
 
 func GetRepoContextName(repoPath string) (string, bool) {
-	// Path should already be absolute and repo root
-	for _, rc := range GlobalConfig.Repositories {
-		if rc.Path == repoPath {
-			return rc.ContextName, true
-		}
-	}
-	return "", false
+	return current.GetRepoContextName(repoPath)
+}
+
+// GetRepoContextName resolves the context of the repository at repoPath. See
+// ResolveRepoContext for the order in which sources are consulted.
+func (s *Store) GetRepoContextName(repoPath string) (string, bool) {
+	r := s.ResolveRepoContext(repoPath)
+	return r.ContextName, r.Found()
 }
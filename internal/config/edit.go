@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is wrapped by edits rejected because validation found errors.
+var ErrInvalidConfig = errors.New("configuration is invalid")
+
+// FilePath returns the path of the current store's config file. It is set by
+// SetCurrent, InitConfig or InitPath.
+func FilePath() string {
+	return current.Path
+}
+
+// InitPath resolves the default config file path for the current store if
+// none was selected, without reading the file. It is enough for commands that
+// must work even when config.yaml is broken (e.g. 'config edit').
+func InitPath() error {
+	if current.Path != "" {
+		return nil
+	}
+	store, err := ResolveStore("", "")
+	if err != nil {
+		return err
+	}
+	current.Path, current.Profile, current.SystemPath = store.Path, store.Profile, store.SystemPath
+	return nil
+}
+
+// GetValue returns the value at a dotted key such as "keyring.backend" or
+// "contexts.work.email". List elements are addressed by name, path or index.
+// Scalars are returned as-is; mappings and lists as YAML.
+func GetValue(key string) (string, error) {
+	return current.GetValue(key)
+}
+
+// GetValue is the Store form of the package-level GetValue.
+func (s *Store) GetValue(key string) (string, error) {
+	file, err := s.readFile()
+	if err != nil {
+		return "", err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(file.doc, &doc); err != nil {
+		return "", err
+	}
+	if len(doc.Content) != 1 {
+		return "", fmt.Errorf("key '%s' is not set", key)
+	}
+	node, err := lookupNode(doc.Content[0], splitKey(key), false)
+	if err != nil {
+		return "", err
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// SetValue sets the scalar at a dotted key (see GetValue), creating missing
+// mapping keys. Unless force is set, the key must be one gham knows about.
+// The result is validated before it is saved.
+func SetValue(key, value string, force bool) ([]Problem, error) {
+	return current.SetValue(key, value, force)
+}
+
+// SetValue is the Store form of the package-level SetValue.
+func (s *Store) SetValue(key, value string, force bool) ([]Problem, error) {
+	segments := splitKey(key)
+	if !force {
+		if err := checkKnownKey(segments); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []Problem
+	err := s.updateDocument(func(root *yaml.Node) error {
+		node, err := lookupNode(root, segments, true)
+		if err != nil {
+			return err
+		}
+		if node.Kind != yaml.ScalarNode && len(node.Content) > 0 {
+			return fmt.Errorf("key '%s' holds a %s; set its individual fields instead", key, kindName(node))
+		}
+		var parsed yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || len(parsed.Content) != 1 || parsed.Content[0].Kind != yaml.ScalarNode {
+			// Not a plain YAML scalar (e.g. contains ': '); store it as a string.
+			parsed = yaml.Node{Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}}}
+		}
+		scalar := parsed.Content[0]
+		node.Kind, node.Tag, node.Value, node.Style, node.Content = yaml.ScalarNode, scalar.Tag, scalar.Value, scalar.Style, nil
+		return nil
+	}, &problems, false)
+	return problems, err
+}
+
+// ReplaceDocument validates data as a complete config file and, if valid,
+// saves it in place of config.yaml (as 'config edit' does).
+func ReplaceDocument(data []byte) ([]Problem, error) {
+	return current.ReplaceDocument(data)
+}
+
+// ReplaceDocument is the Store form of the package-level ReplaceDocument.
+func (s *Store) ReplaceDocument(data []byte) ([]Problem, error) {
+	var problems []Problem
+	err := s.updateDocument(func(root *yaml.Node) error {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nodeError(err)
+		}
+		if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+			return fmt.Errorf("config file must be a YAML mapping")
+		}
+		*root = *doc.Content[0]
+		return nil
+	}, &problems, true)
+	return problems, err
+}
+
+// updateDocument applies fn to the config document under the lock, validates
+// the result and saves it. Validation findings are returned through problems;
+// errors abort the save with ErrInvalidConfig. With replacing set, fn replaces
+// the whole document, so an unreadable current file is not an error.
+func (s *Store) updateDocument(fn func(root *yaml.Node) error, problems *[]Problem, replacing bool) error {
+	if err := s.loadSystem(); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := s.readFile()
+	if err != nil {
+		if !replacing || errors.Is(err, ErrConfigTooNew) {
+			return err
+		}
+		raw, readErr := s.ReadRaw()
+		if readErr != nil {
+			return readErr
+		}
+		file = &configFile{raw: raw, fromVersion: CurrentConfigVersion}
+	}
+	var doc yaml.Node
+	if len(bytes.TrimSpace(file.doc)) > 0 {
+		if err := yaml.Unmarshal(file.doc, &doc); err != nil {
+			return nodeError(err)
+		}
+	}
+	if len(doc.Content) != 1 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if err := fn(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(file.doc))
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	// Upgrade edited documents too, e.g. a pasted snippet from an older gham.
+	edited, _, err := migrateDocument(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	var cfg AppConfig
+	if err := yaml.Unmarshal(edited, &cfg); err != nil {
+		return nodeError(err)
+	}
+	// Validate what gham will actually use, e.g. rules may name system contexts.
+	merged, _ := mergeLayers(s.system, cfg)
+	*problems = Validate(&merged)
+	if HasErrors(*problems) {
+		return ErrInvalidConfig
+	}
+
+	// Save through the normal path so the version key, backup and atomic write apply.
+	file.doc = edited
+	if err := s.writeFile(file, &cfg); err != nil {
+		return err
+	}
+	s.setUser(cfg)
+	return nil
+}
+
+func nodeError(err error) error {
+	return fmt.Errorf("invalid YAML: %w", err)
+}
+
+func splitKey(key string) []string {
+	return strings.Split(strings.Trim(key, "."), ".")
+}
+
+// lookupNode walks a dotted key from root. With create set, missing mapping
+// keys are added; list elements are never created.
+func lookupNode(root *yaml.Node, segments []string, create bool) (*yaml.Node, error) {
+	node := root
+	for i, seg := range segments {
+		walked := strings.Join(segments[:i+1], ".")
+		switch node.Kind {
+		case yaml.MappingNode:
+			next := mappingValue(node, seg)
+			if next == nil {
+				if !create {
+					return nil, fmt.Errorf("key '%s' is not set", walked)
+				}
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if i == len(segments)-1 {
+					next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}, next)
+			}
+			node = next
+		case yaml.SequenceNode:
+			next := sequenceElement(node, seg)
+			if next == nil {
+				return nil, fmt.Errorf("no list element '%s' at '%s'", seg, strings.Join(segments[:i], "."))
+			}
+			node = next
+		case yaml.ScalarNode:
+			if create && node.Tag == "!!null" {
+				// An empty key (e.g. "keyring:") becomes a mapping.
+				node.Kind, node.Tag, node.Value = yaml.MappingNode, "!!map", ""
+				next := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if i == len(segments)-1 {
+					next = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+				}
+				node.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg}, next}
+				node = next
+				continue
+			}
+			return nil, fmt.Errorf("key '%s' is a scalar and has no field '%s'", strings.Join(segments[:i], "."), seg)
+		default:
+			return nil, fmt.Errorf("key '%s' cannot be traversed", walked)
+		}
+	}
+	return node, nil
+}
+
+// sequenceElement finds a list element by identity (name or path) or by index.
+func sequenceElement(seq *yaml.Node, seg string) *yaml.Node {
+	for _, item := range seq.Content {
+		for _, key := range sequenceIdentityKeys {
+			if v := mappingValue(item, key); v != nil && v.Value == seg {
+				return item
+			}
+		}
+	}
+	if idx, err := strconv.Atoi(seg); err == nil && idx >= 0 && idx < len(seq.Content) {
+		return seq.Content[idx]
+	}
+	return nil
+}
+
+// checkKnownKey rejects keys that do not correspond to an AppConfig field,
+// catching typos such as "contexts.work.emial".
+func checkKnownKey(segments []string) error {
+	t := reflect.TypeOf(AppConfig{})
+	for i, seg := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			fieldType, ok := yamlFields(t)[seg]
+			if !ok {
+				return fmt.Errorf("unknown config key '%s' (use --force to set it anyway)", strings.Join(segments[:i+1], "."))
+			}
+			t = fieldType
+		case reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem() // seg selects an element
+		default:
+			return fmt.Errorf("config key '%s' has no field '%s'", strings.Join(segments[:i], "."), seg)
+		}
+	}
+	return nil
+}
+
+func kindName(n *yaml.Node) string {
+	if n.Kind == yaml.SequenceNode {
+		return "list"
+	}
+	return "mapping"
+}
+
+// ReadRaw returns the current store's config file bytes, or nil if it does not exist.
+func ReadRaw() ([]byte, error) {
+	return current.ReadRaw()
+}
+
+// ReadRaw returns the config file's current bytes, or nil if it does not exist.
+func (s *Store) ReadRaw() ([]byte, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvSystemConfig overrides the path of the system-wide config file.
+	// Set it to "none" to ignore the system layer.
+	EnvSystemConfig = "GHAM_SYSTEM_CONFIG"
+
+	// Config layers, from lowest to highest precedence for most settings.
+	LayerSystem = "system"
+	LayerUser   = "user"
+)
+
+// SystemConfigPath returns the path of the read-only, machine-wide config
+// file that is layered under the user's config, or "" if it is disabled.
+func SystemConfigPath() string {
+	if env := strings.TrimSpace(os.Getenv(EnvSystemConfig)); env != "" {
+		if env == "none" {
+			return ""
+		}
+		return env
+	}
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			return ""
+		}
+		return filepath.Join(programData, AppName, ConfigFileName)
+	}
+	return filepath.Join("/etc", AppName, ConfigFileName)
+}
+
+// Setting is one effective configuration value and the layer it came from.
+type Setting struct {
+	Key   string
+	Value string
+	Layer string // LayerSystem or LayerUser
+	File  string
+}
+
+// loadSystem reads the system config file once. gham never writes it; a
+// missing file simply means there is no system layer.
+func (s *Store) loadSystem() error {
+	if s.systemLoaded {
+		return nil
+	}
+	s.systemLoaded = true
+	if s.SystemPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.SystemPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read system config file '%s': %w", s.SystemPath, err)
+	}
+	doc, _, err := migrateDocument(data)
+	if err != nil {
+		return fmt.Errorf("failed to load system config file '%s': %w", s.SystemPath, err)
+	}
+	var cfg AppConfig
+	if err := yaml.Unmarshal(doc, &cfg); err != nil {
+		return fmt.Errorf("failed to unmarshal system config from '%s': %w", s.SystemPath, err)
+	}
+	s.system = &cfg
+	return nil
+}
+
+// setUser records the user layer and recomputes the effective configuration.
+func (s *Store) setUser(user AppConfig) {
+	s.User = user
+	s.Config, s.origins = mergeLayers(s.system, user)
+}
+
+// mergeLayers layers the user config over the system config. The system layer
+// contributes contexts (identity only: its token settings are ignored), path
+// rules and policies; everything else comes from the user layer.
+//   - Contexts: a user context with the same name overrides the identity fields
+//     it sets and supplies the token source.
+//   - Rules: user rules win over system rules for the same directory.
+//   - Policies: system values win, so they cannot be relaxed per user.
+//   - Defaults: the system supplies the global and per-host defaults the user
+//     does not set.
+//
+// The returned map gives the layer of each setting key (see Settings).
+func mergeLayers(system *AppConfig, user AppConfig) (AppConfig, map[string]string) {
+	origins := map[string]string{}
+	merged := user
+	merged.Contexts = make([]Context, 0, len(user.Contexts))
+	merged.Rules = append([]PathRule(nil), user.Rules...)
+	for _, r := range user.Rules {
+		origins["rules."+r.Path] = LayerUser
+	}
+	if system == nil {
+		merged.Contexts = append(merged.Contexts, user.Contexts...)
+		return merged, origins
+	}
+
+	index := map[string]int{}
+	for _, ctx := range system.Contexts {
+		if _, dup := index[ctx.Name]; dup {
+			continue
+		}
+		index[ctx.Name] = len(merged.Contexts)
+		merged.Contexts = append(merged.Contexts, Context{Name: ctx.Name, Username: ctx.Username, Email: ctx.Email})
+		origins["contexts."+ctx.Name] = LayerSystem
+		origins["contexts."+ctx.Name+".username"] = LayerSystem
+		origins["contexts."+ctx.Name+".email"] = LayerSystem
+	}
+	for _, ctx := range user.Contexts {
+		i, ok := index[ctx.Name]
+		if !ok {
+			merged.Contexts = append(merged.Contexts, ctx)
+			continue
+		}
+		base := &merged.Contexts[i]
+		if ctx.Username != "" {
+			base.Username = ctx.Username
+			origins["contexts."+ctx.Name+".username"] = LayerUser
+		}
+		if ctx.Email != "" {
+			base.Email = ctx.Email
+			origins["contexts."+ctx.Name+".email"] = LayerUser
+		}
+		base.TokenSource = ctx.TokenSource
+	}
+
+	for _, r := range system.Rules {
+		if _, overridden := origins["rules."+r.Path]; overridden {
+			continue
+		}
+		merged.Rules = append(merged.Rules, r)
+		origins["rules."+r.Path] = LayerSystem
+	}
+
+	if merged.Defaults.Context == "" && system.Defaults.Context != "" {
+		merged.Defaults.Context = system.Defaults.Context
+		origins["defaults.context"] = LayerSystem
+	}
+	if len(system.Defaults.Hosts) > 0 {
+		merged.Defaults.Hosts = make(map[string]string, len(user.Defaults.Hosts)+len(system.Defaults.Hosts))
+		for host, ctxName := range system.Defaults.Hosts {
+			merged.Defaults.Hosts[host] = ctxName
+			origins["defaults.hosts."+host] = LayerSystem
+		}
+		for host, ctxName := range user.Defaults.Hosts {
+			merged.Defaults.Hosts[host] = ctxName
+			delete(origins, "defaults.hosts."+host)
+		}
+	}
+
+	if len(system.Policies.AllowedTokenSources) > 0 {
+		merged.Policies.AllowedTokenSources = system.Policies.AllowedTokenSources
+		origins["policies.allowedTokenSources"] = LayerSystem
+	}
+	if system.Policies.RequireContext {
+		merged.Policies.RequireContext = true
+		origins["policies.requireContext"] = LayerSystem
+	}
+	return merged, origins
+}
+
+// Settings lists the effective configuration as flat keys, each with the
+// layer and file it came from.
+func (s *Store) Settings() []Setting {
+	var settings []Setting
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		layer, file := s.Origin(key)
+		settings = append(settings, Setting{Key: key, Value: value, Layer: layer, File: file})
+	}
+	cfg := &s.Config
+
+	for _, ctx := range cfg.Contexts {
+		add("contexts."+ctx.Name+".username", ctx.Username)
+		add("contexts."+ctx.Name+".email", ctx.Email)
+		add("contexts."+ctx.Name+".tokenSource", ctx.TokenSourceType())
+	}
+	for _, repo := range cfg.Repositories {
+		add("repositories."+repo.Key(), repo.ContextName)
+	}
+	for _, rule := range cfg.Rules {
+		add("rules."+rule.Path, rule.ContextName)
+	}
+	if len(cfg.Policies.AllowedTokenSources) > 0 {
+		sources := append([]string(nil), cfg.Policies.AllowedTokenSources...)
+		sort.Strings(sources)
+		add("policies.allowedTokenSources", strings.Join(sources, ","))
+	}
+	if cfg.Policies.RequireContext {
+		add("policies.requireContext", strconv.FormatBool(true))
+	}
+	add("defaults.context", cfg.Defaults.Context)
+	for _, host := range cfg.Defaults.DefaultHosts() {
+		add("defaults.hosts."+host, cfg.Defaults.Hosts[host])
+	}
+	add("keyring.backend", cfg.Keyring.Backend)
+	add("keyring.timeout", cfg.Keyring.Timeout)
+	return settings
+}
+
+// Settings lists the current store's effective configuration. See Store.Settings.
+func Settings() []Setting {
+	return current.Settings()
+}
+
+// SystemContext reports whether the system layer defines a context.
+func (s *Store) SystemContext(name string) bool {
+	if s.system == nil {
+		return false
+	}
+	_, found := s.system.findContext(name)
+	return found
+}
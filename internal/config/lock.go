@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	lockFileSuffix = ".lock"
+	lockTimeout    = 10 * time.Second
+	lockRetryDelay = 50 * time.Millisecond
+)
+
+// errLockBusy is returned by tryLockFile when another process holds the lock.
+var errLockBusy = errors.New("lock is held by another process")
+
+// lock takes an exclusive lock on config.yaml.lock, so concurrent gham
+// invocations serialize their read-modify-write cycles. The returned function
+// releases the lock.
+func (s *Store) lock() (func(), error) {
+	if s.Path == "" {
+		return nil, fmt.Errorf("config file path not initialized. Call InitConfig first")
+	}
+	lockPath := s.Path + lockFileSuffix
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock file '%s': %w", lockPath, err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err = tryLockFile(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLockBusy) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock config file '%s': %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for another gham process to release '%s'", lockTimeout, lockPath)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the config.yaml schema version written by this build.
+// Files without a version key predate versioning and are version 0.
+const CurrentConfigVersion = 1
+
+// migration upgrades a config document from one schema version to the next.
+// It edits the YAML node tree directly, so comments and unknown keys survive.
+type migration struct {
+	from        int
+	description string
+	apply       func(root *yaml.Node) error
+}
+
+// migrations must be ordered and contiguous: migrations[i].from == i.
+// To change the schema, bump CurrentConfigVersion and append a step here.
+var migrations = []migration{
+	{
+		from:        0,
+		description: "add schema version",
+		apply:       func(root *yaml.Node) error { return nil }, // Shape unchanged; the version key is set below.
+	},
+}
+
+// ErrConfigTooNew is wrapped by load errors for files written by a newer gham.
+var ErrConfigTooNew = errors.New("config file was written by a newer version of gham")
+
+// migrateDocument upgrades a config document to CurrentConfigVersion. It
+// returns the (possibly rewritten) document and the version it started at.
+func migrateDocument(data []byte) ([]byte, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, CurrentConfigVersion, nil // Empty or non-mapping; left to the decoder to report.
+	}
+	root := doc.Content[0]
+
+	version := 0
+	if v := mappingValue(root, "version"); v != nil {
+		parsed, err := strconv.Atoi(v.Value)
+		if err != nil || parsed < 0 {
+			return nil, 0, fmt.Errorf("invalid config schema version '%s'", v.Value)
+		}
+		version = parsed
+	}
+	if version > CurrentConfigVersion {
+		return nil, version, fmt.Errorf("%w: schema version %d, but this gham supports up to %d. Please upgrade gham", ErrConfigTooNew, version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return data, version, nil
+	}
+
+	for v := version; v < CurrentConfigVersion; v++ {
+		step := migrations[v]
+		if err := step.apply(root); err != nil {
+			return nil, version, fmt.Errorf("config migration from version %d (%s) failed: %w", step.from, step.description, err)
+		}
+	}
+	setVersion(root, CurrentConfigVersion)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(data))
+	if err := enc.Encode(&doc); err != nil {
+		return nil, version, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, version, err
+	}
+	return buf.Bytes(), version, nil
+}
+
+// setVersion sets the version key, adding it at the top of the file if missing.
+func setVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if v := mappingValue(root, "version"); v != nil {
+		v.Value, v.Tag = value, "!!int"
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value}
+	if len(root.Content) > 0 {
+		// Keep a leading file comment at the top of the file.
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, val}, root.Content...)
+}
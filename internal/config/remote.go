@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// NormalizeRemote turns a git remote URL into the identity assignments are
+// keyed by, "host/owner/repo" in lower case. https, ssh and scp-like
+// ("git@host:owner/repo.git") URLs of the same repository are equal.
+func NormalizeRemote(remoteURL string) (string, error) {
+	raw := strings.TrimSpace(remoteURL)
+	var host, path string
+	if i := strings.Index(raw, "://"); i >= 0 {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse remote URL '%s': %w", remoteURL, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(raw, ":"); ok && !strings.Contains(at, "/") {
+		// scp-like syntax: [user@]host:path
+		if _, h, found := strings.Cut(at, "@"); found {
+			at = h
+		}
+		host, path = at, rest
+	} else if strings.Count(raw, "/") == 2 && !strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, ".") {
+		// Already an identity.
+		host, path, _ = strings.Cut(raw, "/")
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", fmt.Errorf("remote URL '%s' has no host and repository path", remoteURL)
+	}
+	return strings.ToLower(host + "/" + path), nil
+}
+
+// RemoteIdentity returns the normalized identity of the origin remote of the
+// repository at repoPath, or "" if it has none.
+func RemoteIdentity(repoPath string) string {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return ""
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	id, err := NormalizeRemote(remote.Config().URLs[0])
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// Section and key of the assignment kept in a repository's own git config.
+const (
+	localConfigSection = "gham"
+	localConfigKey     = "context"
+)
+
+// LocalContextName returns the context recorded as gham.context in the git
+// config of the repository at repoPath.
+func LocalContextName(repoPath string) (string, bool) {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return "", false
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", false
+	}
+	name := cfg.Raw.Section(localConfigSection).Option(localConfigKey)
+	return name, name != ""
+}
+
+// SetLocalContext records contextName as gham.context in the git config of the
+// repository at repoPath, so the assignment travels with the checkout.
+func SetLocalContext(repoPath, contextName string) error {
+	repo, err := openRepo(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository '%s': %w", repoPath, err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read git config of '%s': %w", repoPath, err)
+	}
+	cfg.Raw.Section(localConfigSection).SetOption(localConfigKey, contextName)
+	if err := repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write git config of '%s': %w", repoPath, err)
+	}
+	return nil
+}
+
+func openRepo(repoPath string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
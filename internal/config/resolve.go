@@ -0,0 +1,190 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// Defaults name the context for repositories nothing else assigns one to,
+// and for commands outside a repository such as clone.
+type Defaults struct {
+	Context string            `yaml:"context,omitempty"` // used when nothing more specific applies
+	Hosts   map[string]string `yaml:"hosts,omitempty"`   // origin host (e.g. "github.example.com") -> context
+}
+
+// EnvContext names a context 'gham git' uses instead of the repository's,
+// like its --gham-context flag.
+const EnvContext = "GHAM_CONTEXT"
+
+// Sources a repository's context can be resolved from, in order of precedence.
+const (
+	SourceOverride    = "override"     // --gham-context or GHAM_CONTEXT, for one command
+	SourceGitConfig   = "git-config"   // gham.context in the repository's git config
+	SourcePath        = "path"         // an assignment for the checkout's path
+	SourceRemote      = "remote"       // an assignment for the origin remote
+	SourceRule        = "rule"         // a path rule
+	SourceHostDefault = "host-default" // the default for the origin's host
+	SourceDefault     = "default"      // the global default context
+)
+
+// ResolutionStep is one source consulted while resolving a repository's context.
+type ResolutionStep struct {
+	Source      string `json:"source"`
+	Matched     bool   `json:"matched"`
+	ContextName string `json:"context,omitempty"`
+	Detail      string `json:"detail,omitempty"` // e.g. the rule, remote or host that matched
+}
+
+// Resolution records how a repository's context was determined.
+type Resolution struct {
+	ContextName string           `json:"context,omitempty"`
+	Source      string           `json:"source,omitempty"`
+	Detail      string           `json:"detail,omitempty"`
+	Remote      string           `json:"remote,omitempty"`
+	Steps       []ResolutionStep `json:"steps"`
+}
+
+// Found reports whether any source named a context.
+func (r *Resolution) Found() bool { return r.Source != "" }
+
+func (r *Resolution) step(source, contextName, detail string) bool {
+	matched := contextName != ""
+	r.Steps = append(r.Steps, ResolutionStep{Source: source, Matched: matched, ContextName: contextName, Detail: detail})
+	if matched && r.Source == "" {
+		r.ContextName, r.Source, r.Detail = contextName, source, detail
+	}
+	return matched
+}
+
+// Override makes contextName the resolved context, ahead of every source
+// already consulted. origin says where the override came from.
+func (r *Resolution) Override(contextName, origin string) {
+	r.ContextName, r.Source, r.Detail = contextName, SourceOverride, origin
+	step := ResolutionStep{Source: SourceOverride, Matched: true, ContextName: contextName, Detail: origin}
+	r.Steps = append([]ResolutionStep{step}, r.Steps...)
+}
+
+func ResolveRepoContext(repoPath string) *Resolution {
+	return current.ResolveRepoContext(repoPath)
+}
+
+// ResolveRepoContext determines the context of the repository at repoPath,
+// consulting the sources in order of precedence: gham.context in its git
+// config, an assignment by path, then by remote, a path rule, the default for
+// its origin's host and the global default. Steps lists every source up to the
+// one that matched.
+func (s *Store) ResolveRepoContext(repoPath string) *Resolution {
+	r := &Resolution{Remote: RemoteIdentity(repoPath)}
+	name, _ := LocalContextName(repoPath)
+	if r.step(SourceGitConfig, name, "") {
+		return r
+	}
+
+	var byPath, byRemote string
+	for _, rc := range s.Config.Repositories {
+		switch {
+		case rc.Path != "" && rc.Path == repoPath:
+			byPath = rc.ContextName
+		case rc.Path == "" && rc.Remote != "" && rc.Remote == r.Remote:
+			byRemote = rc.ContextName
+		}
+	}
+	if r.step(SourcePath, byPath, repoPath) {
+		return r
+	}
+	if r.step(SourceRemote, byRemote, r.Remote) {
+		return r
+	}
+
+	var byRule, rulePath string
+	if rule, ok := s.MatchRule(repoPath); ok {
+		byRule, rulePath = rule.ContextName, rule.Path
+	}
+	if r.step(SourceRule, byRule, rulePath) {
+		return r
+	}
+
+	host, _, _ := strings.Cut(r.Remote, "/")
+	if r.step(SourceHostDefault, s.hostDefault(host), host) {
+		return r
+	}
+	r.step(SourceDefault, s.Config.Defaults.Context, "")
+	return r
+}
+
+// DefaultContextName returns the default context for a host (which may be
+// empty): the host's own default, else the global one.
+func (s *Store) DefaultContextName(host string) (name, source string, found bool) {
+	if name := s.hostDefault(host); name != "" {
+		return name, SourceHostDefault, true
+	}
+	if s.Config.Defaults.Context != "" {
+		return s.Config.Defaults.Context, SourceDefault, true
+	}
+	return "", "", false
+}
+
+func DefaultContextName(host string) (name, source string, found bool) {
+	return current.DefaultContextName(host)
+}
+
+func (s *Store) hostDefault(host string) string {
+	if host == "" {
+		return ""
+	}
+	return s.Config.Defaults.Hosts[strings.ToLower(host)]
+}
+
+// TokenHosts returns the hosts the token of context name is meant for: those
+// it is the host default for, or else github.com.
+func (s *Store) TokenHosts(name string) []string {
+	var hosts []string
+	for _, host := range s.Config.Defaults.DefaultHosts() {
+		if s.Config.Defaults.Hosts[host] == name {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{"github.com"}
+	}
+	return hosts
+}
+
+func TokenHosts(name string) []string {
+	return current.TokenHosts(name)
+}
+
+// SetDefaultContext sets the global default context, or a host's default if
+// host is set. An empty contextName removes the default.
+func (s *Store) SetDefaultContext(host, contextName string) error {
+	return s.Update(func(cfg *AppConfig) error {
+		if host == "" {
+			cfg.Defaults.Context = contextName
+			return nil
+		}
+		host = strings.ToLower(host)
+		if contextName == "" {
+			delete(cfg.Defaults.Hosts, host)
+			return nil
+		}
+		if cfg.Defaults.Hosts == nil {
+			cfg.Defaults.Hosts = map[string]string{}
+		}
+		cfg.Defaults.Hosts[host] = contextName
+		return nil
+	})
+}
+
+func SetDefaultContext(host, contextName string) error {
+	return current.SetDefaultContext(host, contextName)
+}
+
+// DefaultHosts returns the hosts that have a default context, sorted.
+func (d Defaults) DefaultHosts() []string {
+	hosts := make([]string, 0, len(d.Hosts))
+	for h := range d.Hosts {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
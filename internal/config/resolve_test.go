@@ -0,0 +1,133 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+// newTestRepo creates a repository whose origin is remoteURL (none if empty).
+func newTestRepo(t *testing.T, remoteURL string) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remoteURL != "" {
+		if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteURL}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveRepoContextOrder(t *testing.T) {
+	const remote = "github.com/acme/api"
+
+	// Every source names its own context; each row removes the ones above it,
+	// so the next source in precedence order must win.
+	tests := []struct {
+		name       string
+		gitConfig  bool
+		byPath     bool
+		byRemote   bool
+		rule       bool
+		hostDef    bool
+		def        bool
+		wantSource string
+		wantSteps  int
+	}{
+		{"git config first", true, true, true, true, true, true, SourceGitConfig, 1},
+		{"then path", false, true, true, true, true, true, SourcePath, 2},
+		{"then remote", false, false, true, true, true, true, SourceRemote, 3},
+		{"then rule", false, false, false, true, true, true, SourceRule, 4},
+		{"then host default", false, false, false, false, true, true, SourceHostDefault, 5},
+		{"then default", false, false, false, false, false, true, SourceDefault, 6},
+		{"nothing", false, false, false, false, false, false, "", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestRepo(t, "https://github.com/acme/api.git")
+			cfg := AppConfig{Defaults: Defaults{Hosts: map[string]string{}}}
+			if tt.gitConfig {
+				if err := SetLocalContext(dir, SourceGitConfig); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.byPath {
+				cfg.Repositories = append(cfg.Repositories, RepoConfig{Path: dir, ContextName: SourcePath})
+			}
+			if tt.byRemote {
+				cfg.Repositories = append(cfg.Repositories, RepoConfig{Remote: remote, ContextName: SourceRemote})
+			}
+			if tt.rule {
+				cfg.Rules = append(cfg.Rules, PathRule{Path: dir, ContextName: SourceRule})
+			}
+			if tt.hostDef {
+				cfg.Defaults.Hosts["github.com"] = SourceHostDefault
+			}
+			if tt.def {
+				cfg.Defaults.Context = SourceDefault
+			}
+			s := &Store{}
+			s.setUser(cfg)
+
+			r := s.ResolveRepoContext(dir)
+			if r.Source != tt.wantSource || r.ContextName != tt.wantSource {
+				t.Errorf("resolved %q from %q, want source %q", r.ContextName, r.Source, tt.wantSource)
+			}
+			if len(r.Steps) != tt.wantSteps {
+				t.Errorf("%d steps, want %d: %+v", len(r.Steps), tt.wantSteps, r.Steps)
+			}
+			if r.Remote != remote {
+				t.Errorf("Remote = %q, want %q", r.Remote, remote)
+			}
+		})
+	}
+}
+
+func TestResolutionOverride(t *testing.T) {
+	dir := newTestRepo(t, "")
+	s := &Store{}
+	s.setUser(AppConfig{Defaults: Defaults{Context: "personal"}})
+
+	r := s.ResolveRepoContext(dir)
+	r.Override("work", "--gham-context")
+	if r.Source != SourceOverride || r.ContextName != "work" {
+		t.Errorf("resolved %q from %q after Override", r.ContextName, r.Source)
+	}
+	if r.Steps[0].Source != SourceOverride || r.Steps[len(r.Steps)-1].ContextName != "personal" {
+		t.Errorf("steps = %+v, want the override first and the default still listed", r.Steps)
+	}
+}
+
+func TestMatchRuleMostSpecific(t *testing.T) {
+	s := &Store{}
+	s.setUser(AppConfig{Rules: []PathRule{
+		{Path: "/src", ContextName: "all"},
+		{Path: "/src/*/client-x", ContextName: "glob"},
+		{Path: "/src/acme", ContextName: "acme"},
+	}})
+	tests := []struct {
+		repo string
+		want string // "" for no match
+	}{
+		{"/src/other/repo", "all"},
+		{"/src/acme/api", "acme"},
+		{"/src/team/client-x/api", "glob"},
+		{"/srcx/repo", ""},
+		{"/elsewhere", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := s.MatchRule(tt.repo)
+		got := ""
+		if ok {
+			got = rule.ContextName
+		}
+		if got != tt.want {
+			t.Errorf("MatchRule(%q) = %q, want %q", tt.repo, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathRule assigns a context to every repository under a directory, so
+// repositories do not need to be assigned one by one.
+type PathRule struct {
+	Path        string `yaml:"path"` // directory, or a glob such as "~/src/*/client-x"; "~/" is expanded
+	ContextName string `yaml:"contextName"`
+}
+
+// Policies restrict what users may configure. Values from the system config
+// take precedence over the user's own.
+type Policies struct {
+	AllowedTokenSources []string `yaml:"allowedTokenSources,omitempty"` // token source types contexts may use; empty allows all
+	RequireContext      bool     `yaml:"requireContext,omitempty"`      // refuse 'gham git' in repositories that resolve to no context
+}
+
+// AllowsTokenSource reports whether contexts may use the given token source type.
+func (p Policies) AllowsTokenSource(sourceType string) bool {
+	if len(p.AllowedTokenSources) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedTokenSources {
+		if allowed == sourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTokenSource returns an error if ctx uses a token source the policies forbid.
+func (p Policies) CheckTokenSource(ctx *Context) error {
+	if !p.AllowsTokenSource(ctx.TokenSourceType()) {
+		return fmt.Errorf("context '%s' uses token source '%s', which is not allowed by policy (allowed: %s)", ctx.Name, ctx.TokenSourceType(), strings.Join(p.AllowedTokenSources, ", "))
+	}
+	return nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// match reports whether repoPath is the rule's directory or inside it. For a
+// glob, the repository or one of its parent directories must match. The
+// returned length ranks matches: the most specific rule wins.
+func (r PathRule) match(repoPath string) (int, bool) {
+	pattern := filepath.Clean(expandHome(r.Path))
+	if !strings.ContainsAny(pattern, "*?[") {
+		if repoPath == pattern || strings.HasPrefix(repoPath, pattern+string(filepath.Separator)) {
+			return len(pattern), true
+		}
+		return 0, false
+	}
+	for dir := repoPath; ; dir = filepath.Dir(dir) {
+		if ok, _ := filepath.Match(pattern, dir); ok {
+			return len(dir), true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return 0, false
+		}
+	}
+}
+
+// MatchRule returns the most specific path rule covering repoPath. Among
+// equally specific rules, user rules win over system rules.
+func (s *Store) MatchRule(repoPath string) (*PathRule, bool) {
+	var best *PathRule
+	bestLen := -1
+	for i := range s.Config.Rules {
+		if n, ok := s.Config.Rules[i].match(repoPath); ok && n > bestLen {
+			best, bestLen = &s.Config.Rules[i], n
+		}
+	}
+	return best, best != nil
+}
+
+// MatchRule returns the most specific path rule in the current store covering repoPath.
+func MatchRule(repoPath string) (*PathRule, bool) {
+	return current.MatchRule(repoPath)
+}
+
+// Origin returns the layer ("system" or "user") and file a setting key came from.
+func (s *Store) Origin(key string) (layer, file string) {
+	if s.origins[key] == LayerSystem {
+		return LayerSystem, s.SystemPath
+	}
+	return LayerUser, s.Path
+}
+
+// Origin returns the layer and file of a setting key in the current store.
+func Origin(key string) (layer, file string) {
+	return current.Origin(key)
+}
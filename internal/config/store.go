@@ -0,0 +1,127 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// EnvConfig points gham at a config file other than the default one.
+	EnvConfig = "GHAM_CONFIG"
+	// EnvProfile selects a named profile, like --profile.
+	EnvProfile = "GHAM_PROFILE"
+
+	profilesDirName = "profiles"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Store is one config file and the configuration last loaded from or saved to
+// it. Commands use the current store (see Current); tests and sandboxes can
+// point gham at a throwaway file with NewStore and SetCurrent.
+type Store struct {
+	Path       string    // config.yaml in use
+	Profile    string    // named profile, "" for the default one
+	SystemPath string    // read-only system config layered under Path; "" for none
+	Config     AppConfig // effective configuration: the system layer merged with User
+	User       AppConfig // the user's own file as of the last load or save
+
+	system       *AppConfig
+	systemLoaded bool
+	origins      map[string]string // setting key -> layer, see Settings
+}
+
+// NewStore returns a store for the config file at path, without a system
+// layer (set SystemPath for one). Nothing is read until Load.
+func NewStore(path, profile string) *Store {
+	return &Store{Path: path, Profile: profile}
+}
+
+var current = &Store{}
+
+// Current returns the store used by the package-level functions.
+func Current() *Store {
+	return current
+}
+
+// SetCurrent replaces the store used by the package-level functions.
+func SetCurrent(s *Store) {
+	current = s
+}
+
+// ValidateProfileName rejects profile names that are not safe as a directory name.
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// ResolveStore picks the config file for this invocation: an explicit path
+// (--config), then $GHAM_CONFIG, then the profile's file, then the default
+// config.yaml. An empty profile falls back to $GHAM_PROFILE.
+func ResolveStore(path, profile string) (*Store, error) {
+	if profile == "" {
+		profile = strings.TrimSpace(os.Getenv(EnvProfile))
+	}
+	if profile != "" {
+		if err := ValidateProfileName(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(EnvConfig))
+	}
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config path '%s': %w", path, err)
+		}
+		store := NewStore(abs, profile)
+		store.SystemPath = SystemConfigPath()
+		return store, nil
+	}
+
+	dir, err := ProfileDir(profile)
+	if err != nil {
+		return nil, err
+	}
+	store := NewStore(filepath.Join(dir, ConfigFileName), profile)
+	store.SystemPath = SystemConfigPath()
+	return store, nil
+}
+
+// ConfigID identifies a config file other than the profile's own, i.e. one
+// given with --config or GHAM_CONFIG, so its keyring tokens can be kept apart:
+// a sandbox config must never read or overwrite the real tokens. It is "" for
+// the profile's own file.
+func (s *Store) ConfigID() string {
+	if s.Path == "" {
+		return ""
+	}
+	path := filepath.Clean(s.Path)
+	if dir, err := ProfileDir(s.Profile); err == nil && path == filepath.Join(dir, ConfigFileName) {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ProfileDir returns the directory holding a profile's default config file:
+// the gham config directory itself for the default profile, or profiles/<name> in it.
+func ProfileDir(profile string) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == "" {
+		return configDir, nil
+	}
+	return filepath.Join(configDir, profilesDirName, profile), nil
+}
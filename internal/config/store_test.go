@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConfigID(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	dir, err := GetConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandbox := filepath.Join(t.TempDir(), "sandbox.yaml")
+
+	tests := []struct {
+		name    string
+		path    string
+		profile string
+		wantID  bool
+	}{
+		{"default file", filepath.Join(dir, ConfigFileName), "", false},
+		{"profile file", filepath.Join(dir, profilesDirName, "client", ConfigFileName), "client", false},
+		{"--config file", sandbox, "", true},
+		{"--config file with a profile", sandbox, "client", true},
+		{"default file with another profile", filepath.Join(dir, ConfigFileName), "client", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := NewStore(tt.path, tt.profile).ConfigID()
+			if (id != "") != tt.wantID {
+				t.Errorf("ConfigID() = %q, want an ID: %v", id, tt.wantID)
+			}
+		})
+	}
+	if a, b := NewStore(sandbox, "").ConfigID(), NewStore(sandbox+"2", "").ConfigID(); a == b {
+		t.Errorf("different config files share the ID %q", a)
+	}
+}
+
+func TestMigrateDocument(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		wantFrom   int
+		wantIn     []string // substrings of the migrated document
+		wantErr    bool
+		wantTooNew bool
+	}{
+		{
+			name:     "unversioned file is upgraded, keeping comments and unknown keys",
+			doc:      "# my gham config\ncontexts: []\nfutureKey: 1 # keep me\n",
+			wantFrom: 0,
+			wantIn:   []string{"# my gham config\nversion: 1\n", "futureKey: 1 # keep me"},
+		},
+		{
+			name:     "current version is left alone",
+			doc:      "version: 1\ncontexts: []\n",
+			wantFrom: CurrentConfigVersion,
+			wantIn:   []string{"version: 1\ncontexts: []\n"},
+		},
+		{name: "newer version", doc: "version: 99\n", wantFrom: 99, wantErr: true, wantTooNew: true},
+		{name: "invalid version", doc: "version: two\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, from, err := migrateDocument([]byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrateDocument() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrConfigTooNew) != tt.wantTooNew {
+				t.Errorf("migrateDocument() error = %v, want ErrConfigTooNew: %v", err, tt.wantTooNew)
+			}
+			if from != tt.wantFrom {
+				t.Errorf("from version = %d, want %d", from, tt.wantFrom)
+			}
+			for _, want := range tt.wantIn {
+				if !strings.Contains(string(doc), want) {
+					t.Errorf("migrated document lacks %q:\n%s", want, doc)
+				}
+			}
+		})
+	}
+}
+
+// newTestStore returns a loaded store for a config file in a temporary
+// directory, holding content if it is not empty.
+func newTestStore(t *testing.T, content string) *Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewStore(path, "")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLoadPersistsUpgrade(t *testing.T) {
+	const old = "contexts:\n  - name: work # day job\n"
+	s := newTestStore(t, old)
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "version: 1\n") || !strings.Contains(string(data), "work # day job") {
+		t.Errorf("upgraded file:\n%s", data)
+	}
+	backup, err := os.ReadFile(s.Path + ".v0" + backupFileSuffix)
+	if err != nil || string(backup) != old {
+		t.Errorf("version backup = %q, %v, want the original file", backup, err)
+	}
+	if len(s.Config.Contexts) != 1 || s.Config.Contexts[0].Name != "work" {
+		t.Errorf("Config.Contexts = %+v", s.Config.Contexts)
+	}
+}
+
+func TestUpdateIsSerialized(t *testing.T) {
+	path := newTestStore(t, "").Path
+
+	// Separate stores, like separate gham processes, each adding a context.
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- NewStore(path, "").Update(func(cfg *AppConfig) error {
+				cfg.Contexts = append(cfg.Contexts, Context{Name: fmt.Sprintf("ctx%d", i)})
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewStore(path, "")
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Config.Contexts) != writers {
+		t.Errorf("%d contexts after %d concurrent updates, want all of them: %+v", len(s.Config.Contexts), writers, s.Config.Contexts)
+	}
+}
+
+func TestUpdateWaitsForLock(t *testing.T) {
+	s := newTestStore(t, "")
+	unlock, err := s.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- NewStore(s.Path, "").Update(func(cfg *AppConfig) error {
+			cfg.Contexts = append(cfg.Contexts, Context{Name: "work"})
+			return nil
+		})
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Update() finished while the lock was held: %v", err)
+	case <-time.After(5 * lockRetryDelay):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
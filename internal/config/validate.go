@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem severities reported by Validate.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is one finding from Validate.
+type Problem struct {
+	Severity string
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+}
+
+// HasErrors reports whether any problem is an error rather than a warning.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validators are extra checks registered by packages that own part of the
+// config (e.g. the keyring backend name), which this package cannot import.
+var validators []func(cfg *AppConfig) []Problem
+
+// RegisterValidator adds a check run by Validate.
+func RegisterValidator(fn func(cfg *AppConfig) []Problem) {
+	validators = append(validators, fn)
+}
+
+// Validate checks a configuration for mistakes that would otherwise only show
+// up when a git command misbehaves.
+func Validate(cfg *AppConfig) []Problem {
+	var problems []Problem
+	errorf := func(format string, args ...any) {
+		problems = append(problems, Problem{Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+	warnf := func(format string, args ...any) {
+		problems = append(problems, Problem{Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+
+	contextNames := map[string]bool{}
+	for i, ctx := range cfg.Contexts {
+		if ctx.Name == "" {
+			errorf("context #%d has no name", i+1)
+			continue
+		}
+		if contextNames[ctx.Name] {
+			errorf("duplicate context name '%s'", ctx.Name)
+		}
+		contextNames[ctx.Name] = true
+
+		if ctx.Email != "" {
+			if addr, err := mail.ParseAddress(ctx.Email); err != nil || addr.Address != ctx.Email {
+				errorf("context '%s' has a malformed email '%s'", ctx.Name, ctx.Email)
+			}
+		}
+		problems = append(problems, validateTokenSource(ctx)...)
+	}
+
+	repoKeys := map[string]bool{}
+	for i, repo := range cfg.Repositories {
+		switch {
+		case repo.Path == "" && repo.Remote == "":
+			errorf("repository assignment #%d has no path or remote", i+1)
+			continue
+		case repo.Path != "" && repo.Remote != "":
+			errorf("repository assignment #%d has both a path and a remote; use one of them", i+1)
+			continue
+		}
+		if repoKeys[repo.Key()] {
+			errorf("repository '%s' is assigned more than once", repo.Key())
+		}
+		repoKeys[repo.Key()] = true
+
+		if !contextNames[repo.ContextName] {
+			errorf("repository '%s' is assigned to context '%s', which does not exist", repo.Key(), repo.ContextName)
+		}
+		if repo.Remote != "" {
+			if id, err := NormalizeRemote(repo.Remote); err != nil || id != repo.Remote {
+				errorf("repository remote '%s' is not of the form host/owner/repo in lower case", repo.Remote)
+			}
+			continue
+		}
+		if fi, err := os.Stat(repo.Path); err != nil {
+			warnf("repository path '%s' does not exist", repo.Path)
+		} else if !fi.IsDir() {
+			warnf("repository path '%s' is not a directory", repo.Path)
+		}
+	}
+
+	ruleDirs := map[string]bool{}
+	for i, rule := range cfg.Rules {
+		if rule.Path == "" {
+			errorf("path rule #%d has no path", i+1)
+			continue
+		}
+		if ruleDirs[rule.Path] {
+			errorf("path rule '%s' is defined more than once", rule.Path)
+		}
+		ruleDirs[rule.Path] = true
+		if !contextNames[rule.ContextName] {
+			errorf("path rule '%s' refers to context '%s', which does not exist", rule.Path, rule.ContextName)
+		}
+	}
+
+	for _, sourceType := range cfg.Policies.AllowedTokenSources {
+		switch sourceType {
+		case TokenSourceKeyring, TokenSourceEnv, TokenSourceCommand, TokenSourceVault:
+		default:
+			errorf("policies.allowedTokenSources has unknown token source type '%s'", sourceType)
+		}
+	}
+	for i := range cfg.Contexts {
+		if err := cfg.Policies.CheckTokenSource(&cfg.Contexts[i]); err != nil {
+			errorf("%v", err)
+		}
+	}
+
+	if cfg.Defaults.Context != "" && !contextNames[cfg.Defaults.Context] {
+		errorf("defaults.context refers to context '%s', which does not exist", cfg.Defaults.Context)
+	}
+	for _, host := range cfg.Defaults.DefaultHosts() {
+		if ctxName := cfg.Defaults.Hosts[host]; !contextNames[ctxName] {
+			errorf("defaults.hosts.%s refers to context '%s', which does not exist", host, ctxName)
+		}
+		if host != strings.ToLower(host) || strings.ContainsAny(host, "/:") {
+			errorf("defaults.hosts key '%s' must be a lower-case host name such as 'github.com'", host)
+		}
+	}
+
+	if cfg.Sync != nil {
+		if cfg.Sync.Remote == "" {
+			errorf("sync.remote is empty")
+		}
+		if cfg.Sync.Context != "" && !contextNames[cfg.Sync.Context] {
+			errorf("sync.context refers to context '%s', which does not exist", cfg.Sync.Context)
+		}
+	}
+
+	if cfg.Keyring.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Keyring.Timeout); err != nil || d <= 0 {
+			errorf("keyring.timeout '%s' is not a positive duration such as '10s'", cfg.Keyring.Timeout)
+		}
+	}
+
+	for _, fn := range validators {
+		problems = append(problems, fn(cfg)...)
+	}
+	return problems
+}
+
+func validateTokenSource(ctx Context) []Problem {
+	errorf := func(format string, args ...any) []Problem {
+		return []Problem{{Severity: SeverityError, Message: fmt.Sprintf(format, args...)}}
+	}
+	switch ctx.TokenSourceType() {
+	case TokenSourceKeyring:
+	case TokenSourceEnv:
+		if ctx.TokenSource.Env == "" {
+			return errorf("context '%s' uses an env token source but sets no 'env' variable name", ctx.Name)
+		}
+	case TokenSourceCommand:
+		if ctx.TokenSource.Command == "" {
+			return errorf("context '%s' uses a command token source but sets no 'command'", ctx.Name)
+		}
+	case TokenSourceVault:
+		v := ctx.TokenSource.Vault
+		if v == nil {
+			return errorf("context '%s' uses a vault token source but has no 'vault' settings", ctx.Name)
+		}
+		if v.Engine != "" && v.Engine != VaultEngineKV2 && v.Engine != VaultEngineGitHub {
+			return errorf("context '%s' has unknown vault engine '%s'", ctx.Name, v.Engine)
+		}
+		if (v.Engine == "" || v.Engine == VaultEngineKV2) && v.Path == "" {
+			return errorf("context '%s' uses a vault kv2 token source but sets no 'path'", ctx.Name)
+		}
+		if v.Auth.Method != "" && v.Auth.Method != VaultAuthToken && v.Auth.Method != VaultAuthAppRole {
+			return errorf("context '%s' has unknown vault auth method '%s'", ctx.Name, v.Auth.Method)
+		}
+		if v.Auth.Method == VaultAuthAppRole && v.Auth.RoleID == "" {
+			return errorf("context '%s' uses vault approle auth but sets no 'roleId'", ctx.Name)
+		}
+	default:
+		return errorf("context '%s' has unknown token source type '%s'", ctx.Name, ctx.TokenSource.Type)
+	}
+	return nil
+}
+
+// ValidateDocument checks the content of a config file as gham would use it:
+// upgraded to the current schema and layered over the current store's system
+// config. It returns the file's own configuration along with the problems.
+func ValidateDocument(data []byte) (*AppConfig, []Problem, error) {
+	if err := current.loadSystem(); err != nil {
+		return nil, nil, err
+	}
+	doc, _, err := migrateDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cfg AppConfig
+	if err := yaml.Unmarshal(doc, &cfg); err != nil {
+		return nil, nil, nodeError(err)
+	}
+	merged, _ := mergeLayers(current.system, cfg)
+	return &cfg, Validate(&merged), nil
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	existing := t.TempDir()
+	work := []Context{{Name: "work", Email: "me@work.example"}}
+	command := &TokenSource{Type: TokenSourceCommand, Command: "op read op://work/gh"}
+
+	tests := []struct {
+		name      string
+		cfg       AppConfig
+		wantError string // substring of the only error; "" for none
+		wantWarn  string // substring of a warning
+	}{
+		{name: "valid", cfg: AppConfig{
+			Contexts:     work,
+			Repositories: []RepoConfig{{Path: existing, ContextName: "work"}, {Remote: "github.com/acme/api", ContextName: "work"}},
+			Rules:        []PathRule{{Path: "~/work", ContextName: "work"}},
+			Defaults:     Defaults{Context: "work", Hosts: map[string]string{"github.com": "work"}},
+			Keyring:      KeyringConfig{Timeout: "5s"},
+		}},
+		{name: "unnamed context", cfg: AppConfig{Contexts: []Context{{}}}, wantError: "has no name"},
+		{name: "duplicate context", cfg: AppConfig{Contexts: append(work, work...)}, wantError: "duplicate context name 'work'"},
+		{name: "malformed email", cfg: AppConfig{Contexts: []Context{{Name: "work", Email: "Me <me@work.example>"}}}, wantError: "malformed email"},
+		{name: "command without a command", cfg: AppConfig{Contexts: []Context{{Name: "work", TokenSource: &TokenSource{Type: TokenSourceCommand}}}}, wantError: "sets no 'command'"},
+		{name: "vault without settings", cfg: AppConfig{Contexts: []Context{{Name: "work", TokenSource: &TokenSource{Type: TokenSourceVault}}}}, wantError: "no 'vault' settings"},
+		{name: "unknown token source", cfg: AppConfig{Contexts: []Context{{Name: "work", TokenSource: &TokenSource{Type: "ldap"}}}}, wantError: "unknown token source type"},
+		{name: "assignment without key", cfg: AppConfig{Contexts: work, Repositories: []RepoConfig{{ContextName: "work"}}}, wantError: "no path or remote"},
+		{name: "assignment with both keys", cfg: AppConfig{Contexts: work, Repositories: []RepoConfig{{Path: existing, Remote: "github.com/acme/api", ContextName: "work"}}}, wantError: "both a path and a remote"},
+		{name: "assignment to a missing context", cfg: AppConfig{Repositories: []RepoConfig{{Remote: "github.com/acme/api", ContextName: "work"}}}, wantError: "does not exist"},
+		{name: "duplicate assignment", cfg: AppConfig{Contexts: work, Repositories: []RepoConfig{{Remote: "github.com/acme/api", ContextName: "work"}, {Remote: "github.com/acme/api", ContextName: "work"}}}, wantError: "assigned more than once"},
+		{name: "unnormalized remote", cfg: AppConfig{Contexts: work, Repositories: []RepoConfig{{Remote: "https://github.com/Acme/API.git", ContextName: "work"}}}, wantError: "host/owner/repo"},
+		{name: "missing repository path", cfg: AppConfig{Contexts: work, Repositories: []RepoConfig{{Path: existing + "/gone", ContextName: "work"}}}, wantWarn: "does not exist"},
+		{name: "rule to a missing context", cfg: AppConfig{Rules: []PathRule{{Path: "~/work", ContextName: "work"}}}, wantError: "refers to context 'work'"},
+		{name: "duplicate rule", cfg: AppConfig{Contexts: work, Rules: []PathRule{{Path: "~/work", ContextName: "work"}, {Path: "~/work", ContextName: "work"}}}, wantError: "defined more than once"},
+		{name: "unknown policy source", cfg: AppConfig{Policies: Policies{AllowedTokenSources: []string{"ldap"}}}, wantError: "unknown token source type 'ldap'"},
+		{name: "context against policy", cfg: AppConfig{Contexts: []Context{{Name: "work", TokenSource: command}}, Policies: Policies{AllowedTokenSources: []string{TokenSourceKeyring}}}, wantError: "not allowed by policy"},
+		{name: "missing default context", cfg: AppConfig{Defaults: Defaults{Context: "work"}}, wantError: "defaults.context"},
+		{name: "host default with a URL", cfg: AppConfig{Contexts: work, Defaults: Defaults{Hosts: map[string]string{"https://github.com": "work"}}}, wantError: "lower-case host name"},
+		{name: "sync without remote", cfg: AppConfig{Sync: &SyncConfig{}}, wantError: "sync.remote is empty"},
+		{name: "bad keyring timeout", cfg: AppConfig{Keyring: KeyringConfig{Timeout: "-1s"}}, wantError: "positive duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate(&tt.cfg)
+			var errs, warnings []string
+			for _, p := range problems {
+				if p.Severity == SeverityError {
+					errs = append(errs, p.Message)
+				} else {
+					warnings = append(warnings, p.Message)
+				}
+			}
+			if HasErrors(problems) != (tt.wantError != "") {
+				t.Fatalf("Validate() errors = %q, want one mentioning %q", errs, tt.wantError)
+			}
+			if tt.wantError != "" && (len(errs) != 1 || !strings.Contains(errs[0], tt.wantError)) {
+				t.Errorf("Validate() errors = %q, want only one mentioning %q", errs, tt.wantError)
+			}
+			if tt.wantWarn != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.wantWarn)) {
+				t.Errorf("Validate() warnings = %q, want one mentioning %q", warnings, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	t.Setenv(EnvSystemConfig, "none")
+	s := newTestStore(t, "contexts:\n  - name: work # day job\n")
+
+	if _, err := s.SetValue("contexts.work.email", "me@work.example", false); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetValue("contexts.work.email"); err != nil || got != "me@work.example" {
+		t.Errorf("GetValue() = %q, %v, want the value just set", got, err)
+	}
+	if _, err := s.SetValue("keyring.timeout", "30s", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SetValue("contexts.work.emial", "x", false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("SetValue() of a misspelled key = %v, want it refused", err)
+	}
+	if _, err := s.SetValue("futureKey", "1", true); err != nil {
+		t.Errorf("SetValue() of an unknown key with force = %v", err)
+	}
+	problems, err := s.SetValue("contexts.work.email", "not an email", false)
+	if !errors.Is(err, ErrInvalidConfig) || !HasErrors(problems) {
+		t.Errorf("SetValue() of an invalid value = %v, %v, want ErrInvalidConfig", problems, err)
+	}
+	if _, err := s.SetValue("contexts.work", "x", false); err == nil {
+		t.Error("SetValue() replaced a mapping with a scalar")
+	}
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# day job", "email: me@work.example", "timeout: 30s", "futureKey: 1"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config file lacks %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "not an email") {
+		t.Errorf("an invalid edit was saved:\n%s", data)
+	}
+}
+
+func TestReplaceDocument(t *testing.T) {
+	t.Setenv(EnvSystemConfig, "none")
+	s := newTestStore(t, "contexts:\n  - name: work\n")
+
+	if _, err := s.ReplaceDocument([]byte("contexts: [oops")); err == nil {
+		t.Error("ReplaceDocument() accepted invalid YAML")
+	}
+	if _, err := s.ReplaceDocument([]byte("rules:\n  - path: ~/x\n    contextName: nobody\n")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("ReplaceDocument() of an invalid config = %v, want ErrInvalidConfig", err)
+	}
+	if _, err := s.ReplaceDocument([]byte("contexts:\n  - name: personal\n")); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Config.Contexts) != 1 || s.Config.Contexts[0].Name != "personal" {
+		t.Errorf("contexts after ReplaceDocument() = %+v", s.Config.Contexts)
+	}
+}
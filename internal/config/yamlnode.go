@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sequenceIdentityKeys are the fields that identify an element of a list of
+// mappings (contexts by name, repositories by path, or by remote if they have
+// no path), so an element keeps its comments and unknown fields when the list
+// is reordered or edited.
+var sequenceIdentityKeys = []string{"name", "path", "remote"}
+
+// marshalPreserving encodes cfg into the document previously read from disk,
+// changing only what differs. Comments, key order and keys this build does not
+// know about (e.g. written by a newer gham) survive the round trip.
+func marshalPreserving(cfg any, original []byte) ([]byte, error) {
+	var updated yaml.Node
+	if err := updated.Encode(cfg); err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := yaml.Unmarshal(original, &doc); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		mergeNode(doc.Content[0], &updated, reflect.TypeOf(cfg))
+	} else {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(original))
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeNode updates dst in place to carry src's values. t is the Go type src
+// was encoded from; it tells known keys (removed from dst when src omits them)
+// apart from unknown keys (always kept).
+func mergeNode(dst, src *yaml.Node, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if dst.Kind != src.Kind {
+		replaceNode(dst, src)
+		return
+	}
+
+	switch src.Kind {
+	case yaml.MappingNode:
+		mergeMapping(dst, src, t)
+	case yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		mergeSequence(dst, src, elem)
+	default:
+		if dst.Value != src.Value || dst.Tag != src.Tag {
+			dst.Value, dst.Tag, dst.Style = src.Value, src.Tag, src.Style
+		}
+	}
+}
+
+func mergeMapping(dst, src *yaml.Node, t reflect.Type) {
+	fields := yamlFields(t)
+	srcKeys := make(map[string]bool, len(src.Content)/2)
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		srcKeys[key.Value] = true
+		var fieldType reflect.Type
+		if t != nil && t.Kind() == reflect.Map {
+			fieldType = t.Elem()
+		} else {
+			fieldType = fields[key.Value]
+		}
+		if existing := mappingValue(dst, key.Value); existing != nil {
+			mergeNode(existing, value, fieldType)
+		} else {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+
+	// Drop keys this build owns but no longer emits (e.g. omitempty fields now empty).
+	isMap := t != nil && t.Kind() == reflect.Map
+	kept := dst.Content[:0]
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key := dst.Content[i].Value
+		_, known := fields[key]
+		if !srcKeys[key] && (known || isMap) {
+			continue
+		}
+		kept = append(kept, dst.Content[i], dst.Content[i+1])
+	}
+	dst.Content = kept
+}
+
+func mergeSequence(dst, src *yaml.Node, elem reflect.Type) {
+	existing := make(map[string]*yaml.Node, len(dst.Content))
+	for _, item := range dst.Content {
+		if id := sequenceIdentity(item); id != "" {
+			existing[id] = item
+		}
+	}
+
+	merged := make([]*yaml.Node, 0, len(src.Content))
+	for i, item := range src.Content {
+		var match *yaml.Node
+		if id := sequenceIdentity(item); id != "" {
+			match = existing[id]
+			delete(existing, id) // Never merge two elements into the same node.
+		} else if i < len(dst.Content) && sequenceIdentity(dst.Content[i]) == "" {
+			match = dst.Content[i]
+		}
+		if match != nil {
+			mergeNode(match, item, elem)
+			merged = append(merged, match)
+		} else {
+			merged = append(merged, item)
+		}
+	}
+	dst.Content = merged
+	if len(merged) > 0 {
+		dst.Style &^= yaml.FlowStyle // An empty "[]" list that gained elements becomes a block list.
+	}
+}
+
+// replaceNode overwrites dst with src, keeping the comments attached to dst.
+func replaceNode(dst, src *yaml.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	if dst.HeadComment == "" {
+		dst.HeadComment = head
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = line
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = foot
+	}
+}
+
+func sequenceIdentity(item *yaml.Node) string {
+	if item.Kind != yaml.MappingNode {
+		return ""
+	}
+	for _, key := range sequenceIdentityKeys {
+		if v := mappingValue(item, key); v != nil && v.Kind == yaml.ScalarNode {
+			return key + "=" + v.Value
+		}
+	}
+	return ""
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlFields maps the YAML keys of a struct type to their field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// detectIndent returns the indentation used by a YAML document, defaulting to
+// the 4 spaces gham has always written.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent := len(line) - len(trimmed); indent >= 2 && indent <= 8 {
+			return indent
+		}
+		break
+	}
+	return 4
+}
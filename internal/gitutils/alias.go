@@ -0,0 +1,202 @@
+package gitutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	gc "github.com/go-git/go-git/v5/config"
+)
+
+// maxAliasDepth bounds alias chains like git does to catch loops early.
+const maxAliasDepth = 16
+
+// gitCommands are git's own commands. As in git, an alias with one of these
+// names is ignored.
+var gitCommands = map[string]bool{
+	"add": true, "am": true, "annotate": true, "apply": true, "archive": true, "bisect": true,
+	"blame": true, "branch": true, "bundle": true, "cat-file": true, "check-ignore": true,
+	"checkout": true, "cherry": true, "cherry-pick": true, "clean": true, "clone": true,
+	"commit": true, "config": true, "describe": true, "diff": true, "difftool": true,
+	"fetch": true, "format-patch": true, "fsck": true, "gc": true, "grep": true, "help": true,
+	"init": true, "log": true, "ls-files": true, "ls-remote": true, "ls-tree": true,
+	"maintenance": true, "merge": true, "mergetool": true, "mv": true, "notes": true,
+	"pull": true, "push": true, "range-diff": true, "rebase": true, "reflog": true,
+	"remote": true, "repack": true, "replace": true, "reset": true, "restore": true,
+	"rev-list": true, "rev-parse": true, "revert": true, "rm": true, "shortlog": true,
+	"show": true, "show-ref": true, "sparse-checkout": true, "stash": true, "status": true,
+	"submodule": true, "switch": true, "tag": true, "version": true, "worktree": true,
+}
+
+// expandAliases replaces a leading alias in args (e.g. 'p' for 'push') with
+// its definition from the effective git config, repeatedly, and applies any
+// global options the definition starts with to globals. shell reports that
+// the command is a shell alias ('!cmd'), which only git itself can run.
+func expandAliases(globals *GlobalOptions, repo *Repo, args []string) (expanded []string, shell bool, err error) {
+	seen := map[string]bool{}
+	for len(args) > 0 && !gitCommands[args[0]] {
+		name := args[0]
+		value, found := lookupAlias(globals, repo, name)
+		if !found {
+			return args, false, nil
+		}
+		if seen[name] || len(seen) >= maxAliasDepth {
+			return nil, false, fmt.Errorf("alias loop detected: expansion of '%s' does not terminate", name)
+		}
+		seen[name] = true
+		if strings.HasPrefix(value, "!") {
+			return args, true, nil
+		}
+		words, err := splitCommandLine(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("bad alias.%s string: %w", name, err)
+		}
+		if len(words) == 0 {
+			return nil, false, fmt.Errorf("empty alias for %s", name)
+		}
+		more, rest, err := ParseGlobalOptions(words, globals.Dir)
+		if err != nil {
+			return nil, false, fmt.Errorf("alias '%s': %w", name, err)
+		}
+		globals.merge(more)
+		args = append(rest, args[1:]...)
+	}
+	return args, false, nil
+}
+
+// merge adds the global options of an expanded alias to g.
+func (g *GlobalOptions) merge(o *GlobalOptions) {
+	g.Args = append(g.Args, o.Args...)
+	g.Dir = o.Dir // o started from g.Dir, so -C is already cumulative.
+	if o.GitDir != "" {
+		g.GitDir = o.GitDir
+	}
+	if o.WorkTree != "" {
+		g.WorkTree = o.WorkTree
+	}
+	g.Bare = g.Bare || o.Bare
+	g.Config = append(g.Config, o.Config...)
+	g.Other = g.Other || o.Other
+}
+
+// lookupAlias finds alias.<name> the way git does: -c options first, then the
+// repository's config, then the global and system config files.
+func lookupAlias(globals *GlobalOptions, repo *Repo, name string) (string, bool) {
+	key := "alias." + strings.ToLower(name)
+	for i := len(globals.Config) - 1; i >= 0; i-- {
+		k, v, _ := strings.Cut(globals.Config[i], "=")
+		if strings.ToLower(k) == key {
+			return v, true
+		}
+	}
+	return ConfigValue(repo, "alias", name)
+}
+
+// ConfigValue reads the effective value of section.key for repo (which may be
+// nil). git itself is asked, so include.path, includeIf and the worktree config
+// apply; without a git executable the config files are read directly.
+func ConfigValue(repo *Repo, section, key string) (string, bool) {
+	if value, found, err := gitConfigGet(repo, section+"."+key); err == nil {
+		return value, found
+	}
+	for _, file := range gitConfigFiles(repo) {
+		if value, ok := readConfigOption(file, section, key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// gitConfigGet runs 'git config --get name' against repo. git exits with 1
+// when the key is not set.
+func gitConfigGet(repo *Repo, name string) (string, bool, error) {
+	var args []string
+	if repo != nil {
+		args = append(args, "--git-dir", repo.GitDir)
+		if repo.WorkTree != "" {
+			args = append(args, "--work-tree", repo.WorkTree)
+		}
+	}
+	out, err := exec.Command("git", append(args, "config", "--get", name)...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(string(out), "\n"), true, nil
+}
+
+// gitConfigFiles lists the config files git reads, highest precedence first,
+// without following includes.
+func gitConfigFiles(repo *Repo) []string {
+	var files []string
+	if repo != nil {
+		files = append(files, filepath.Join(repo.CommonDir, "config"))
+	}
+	if env := os.Getenv("GIT_CONFIG_GLOBAL"); env != "" {
+		files = append(files, env)
+	} else if global, err := gc.Paths(gc.GlobalScope); err == nil {
+		// git reads $XDG_CONFIG_HOME/git/config before ~/.gitconfig, so the latter wins.
+		for i := len(global) - 1; i >= 0; i-- {
+			files = append(files, global[i])
+		}
+	}
+	if env := os.Getenv("GIT_CONFIG_SYSTEM"); env != "" {
+		files = append(files, env)
+	} else if os.Getenv("GIT_CONFIG_NOSYSTEM") == "" {
+		if system, err := gc.Paths(gc.SystemScope); err == nil {
+			files = append(files, system...)
+		}
+	}
+	return files
+}
+
+// splitCommandLine splits an alias definition into words, honoring quotes and
+// backslash escapes like git's split_cmdline.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unclosed quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,145 @@
+package gitutils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"push --force-with-lease", []string{"push", "--force-with-lease"}, false},
+		{"  log\t--oneline \n", []string{"log", "--oneline"}, false},
+		{`commit -m "two words"`, []string{"commit", "-m", "two words"}, false},
+		{`log --format='%h %s'`, []string{"log", "--format=%h %s"}, false},
+		{`log --grep=a\ b`, []string{"log", "--grep=a b"}, false},
+		{`log --grep="say \"hi\""`, []string{"log", "--grep=say \"hi\""}, false},
+		{`log --grep='a\b'`, []string{"log", `--grep=a\b`}, false},
+		{`commit -m ""`, []string{"commit", "-m", ""}, false},
+		{"", nil, false},
+		{`log "unclosed`, nil, true},
+		{`log trailing\`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, %v, want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// useGitConfig points git at a global config file with content, and at no
+// system config.
+func useGitConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	global := filepath.Join(dir, "gitconfig")
+	if err := os.WriteFile(global, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_DIR", "")
+	t.Setenv("GIT_WORK_TREE", "")
+}
+
+func TestExpandAliases(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := newRepoLayout(t)
+	includes := filepath.Join(root, "aliases.inc")
+	if err := os.WriteFile(includes, []byte("[alias]\n\tp = push\n\tpf = p --force-with-lease\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	work := filepath.Join(root, "work.inc")
+	if err := os.WriteFile(work, []byte("[alias]\n\tsync = pull --rebase\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	useGitConfig(t, "[include]\n\tpath = "+includes+"\n"+
+		"[includeIf \"gitdir:"+filepath.Join(root, "main")+"/\"]\n\tpath = "+work+"\n"+
+		"[alias]\n\tst = status --short\n\tsh = !echo hi\n\there = -C src status\n"+
+		"\tloop1 = loop2\n\tloop2 = loop1\n\tpush = log\n")
+	local := filepath.Join(root, "main", ".git", "config")
+	f, err := os.OpenFile(local, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("[alias]\n\tst = status\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	repo, err := FindRepo(filepath.Join(root, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		config    []string // -c options
+		want      []string
+		wantShell bool
+		wantDir   string
+		wantErr   bool
+	}{
+		{name: "not an alias", args: []string{"fetch", "origin"}, want: []string{"fetch", "origin"}},
+		{name: "unknown", args: []string{"nope"}, want: []string{"nope"}},
+		{name: "from include.path", args: []string{"p", "origin"}, want: []string{"push", "origin"}},
+		{name: "from includeIf", args: []string{"sync"}, want: []string{"pull", "--rebase"}},
+		{name: "chained", args: []string{"pf"}, want: []string{"push", "--force-with-lease"}},
+		{name: "repository config wins", args: []string{"st"}, want: []string{"status"}},
+		{name: "-c wins", args: []string{"st"}, config: []string{"alias.st=log"}, want: []string{"log"}},
+		{name: "git commands cannot be aliased", args: []string{"push"}, want: []string{"push"}},
+		{name: "shell alias", args: []string{"sh"}, want: []string{"sh"}, wantShell: true},
+		{name: "global options", args: []string{"here"}, want: []string{"status"}, wantDir: filepath.Join(root, "main", "src")},
+		{name: "loop", args: []string{"loop1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globals := &GlobalOptions{Dir: filepath.Join(root, "main"), Config: tt.config}
+			got, shell, err := expandAliases(globals, repo, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandAliases() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) || shell != tt.wantShell {
+				t.Errorf("expandAliases() = %q, shell %v, want %q, shell %v", got, shell, tt.want, tt.wantShell)
+			}
+			if tt.wantDir != "" && globals.Dir != tt.wantDir {
+				t.Errorf("Dir = %q, want %q", globals.Dir, tt.wantDir)
+			}
+		})
+	}
+
+	// Outside the repository the includeIf does not apply.
+	if got, _, _ := expandAliases(&GlobalOptions{Dir: root}, nil, []string{"sync"}); !reflect.DeepEqual(got, []string{"sync"}) {
+		t.Errorf("expandAliases() outside the repository = %q, want it unexpanded", got)
+	}
+}
+
+func TestConfigValueWithoutGit(t *testing.T) {
+	root := newRepoLayout(t)
+	useGitConfig(t, "[commit]\n\tgpgsign = true\n[alias]\n\tst = status --short\n")
+	t.Setenv("PATH", "")
+	repo, err := FindRepo(filepath.Join(root, "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := ConfigValue(repo, "commit", "gpgsign"); !ok || got != "true" {
+		t.Errorf("ConfigValue(commit.gpgsign) = %q, %v, want the global value", got, ok)
+	}
+	if got, ok := ConfigValue(repo, "core", "bare"); !ok || got != "false" {
+		t.Errorf("ConfigValue(core.bare) = %q, %v, want the repository value", got, ok)
+	}
+	if _, ok := ConfigValue(repo, "alias", "nope"); ok {
+		t.Error("ConfigValue() found an unset key")
+	}
+}
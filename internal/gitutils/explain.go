@@ -0,0 +1,77 @@
+package gitutils
+
+import (
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/tokensource"
+)
+
+// Explanation describes what 'gham git' would do in a repository: which
+// context it uses and why, whether the token can be retrieved, and what is
+// added to the git command.
+type Explanation struct {
+	Root      string `json:"root"`
+	WorkTree  string `json:"workTree,omitempty"`
+	GitDir    string `json:"gitDir"`
+	CommonDir string `json:"commonDir"`
+	Kind      string `json:"kind"`
+
+	Resolution *config.Resolution `json:"resolution"`
+	// ContextMissing is set when the resolved context is not defined.
+	ContextMissing bool `json:"contextMissing,omitempty"`
+
+	TokenSource    string `json:"tokenSource,omitempty"`
+	TokenRetrieved bool   `json:"tokenRetrieved"`
+	TokenError     string `json:"tokenError,omitempty"`
+
+	// Settings are the -c settings added to git commands talking to origin,
+	// and HelperUsername the username the credential helper answers with. Both
+	// are empty when no token is injected.
+	Settings       []string `json:"settings,omitempty"`
+	HelperUsername string   `json:"helperUsername,omitempty"`
+	// GoGit reports that push, pull and fetch to origin run through go-git
+	// rather than git, authenticating with the token directly.
+	GoGit bool `json:"goGit"`
+	// Refused is set when policies.requireContext stops commands here.
+	Refused bool `json:"refused,omitempty"`
+}
+
+// Explain works out what 'gham git' would do in repo, following the same steps
+// as ExecuteGitCommandWithContext, including a GHAM_CONTEXT override.
+// Retrieving the token may prompt, as it would for a git command.
+func Explain(repo *Repo) *Explanation {
+	e := &Explanation{
+		Root:       repo.Root,
+		WorkTree:   repo.WorkTree,
+		GitDir:     repo.GitDir,
+		CommonDir:  repo.CommonDir,
+		Kind:       repo.Kind,
+		Resolution: config.ResolveRepoContext(repo.Root),
+	}
+	if name, origin := ContextOverride(""); name != "" {
+		e.Resolution.Override(name, origin)
+	}
+	if !e.Resolution.Found() {
+		e.Refused = config.Current().Config.Policies.RequireContext
+		return e
+	}
+	ctx, found := config.FindContext(e.Resolution.ContextName)
+	if !found {
+		e.ContextMissing = true
+		e.Refused = config.Current().Config.Policies.RequireContext
+		return e
+	}
+
+	e.TokenSource = ctx.TokenSourceType()
+	if src, err := tokensource.For(ctx); err == nil {
+		e.TokenSource = src.String()
+	}
+	if _, err := tokensource.Resolve(ctx); err != nil {
+		e.TokenError = err.Error()
+		return e
+	}
+	e.TokenRetrieved = true
+	e.Settings = contextSettings(credentialHost(repo, nil), ctx)
+	e.HelperUsername = helperUsername(ctx)
+	e.GoGit = true
+	return e
+}
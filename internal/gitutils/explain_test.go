@@ -0,0 +1,140 @@
+package gitutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// useConfig makes a config file with content the current store.
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), config.ConfigFileName)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := config.NewStore(path, "")
+	if err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	saved := config.Current()
+	config.SetCurrent(store)
+	t.Cleanup(func() { config.SetCurrent(saved) })
+}
+
+// newOriginRepo creates a repository whose origin is on github.com.
+func newOriginRepo(t *testing.T) *Repo {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/acme/api.git"}}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := FindRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestExplain(t *testing.T) {
+	r := newOriginRepo(t)
+	t.Setenv(config.EnvContext, "")
+	const contexts = `version: 1
+contexts:
+  - name: work
+    username: me
+    email: me@acme.example
+    tokenSource:
+      type: env
+      env: GHAM_TEST_WORK_TOKEN
+`
+	tests := []struct {
+		name     string
+		config   string
+		token    string // GHAM_TEST_WORK_TOKEN, unset if empty
+		want     Explanation
+		wantFrom string // resolution source
+	}{
+		{
+			name:     "token retrieved",
+			config:   contexts + "rules:\n  - path: " + r.Root + "\n    contextName: work\n",
+			token:    "ghp_work",
+			wantFrom: config.SourceRule,
+			want: Explanation{
+				TokenSource:    "env:GHAM_TEST_WORK_TOKEN",
+				TokenRetrieved: true,
+				Settings: []string{
+					"credential.helper=",
+					"credential.https://github.com.helper=" + credentialHelper,
+					"user.name=me",
+					"user.email=me@acme.example",
+				},
+				HelperUsername: "me",
+				GoGit:          true,
+			},
+		},
+		{
+			name:     "token missing",
+			config:   contexts + "defaults:\n  hosts:\n    github.com: work\n",
+			wantFrom: config.SourceHostDefault,
+			want: Explanation{
+				TokenSource: "env:GHAM_TEST_WORK_TOKEN",
+				TokenError:  "environment variable 'GHAM_TEST_WORK_TOKEN' is not set",
+			},
+		},
+		{
+			name:     "global default",
+			config:   contexts + "defaults:\n  context: work\n",
+			wantFrom: config.SourceDefault,
+			want:     Explanation{TokenSource: "env:GHAM_TEST_WORK_TOKEN", TokenError: "environment variable 'GHAM_TEST_WORK_TOKEN' is not set"},
+		},
+		{
+			name:   "nothing resolved",
+			config: contexts + "policies:\n  requireContext: true\n",
+			want:   Explanation{Refused: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			if tt.token != "" {
+				t.Setenv("GHAM_TEST_WORK_TOKEN", tt.token)
+			} else {
+				os.Unsetenv("GHAM_TEST_WORK_TOKEN")
+			}
+			e := Explain(r)
+			if e.Root != r.Root || e.Kind != KindRepository || e.Resolution.Remote != "github.com/acme/api" {
+				t.Errorf("Explain() repository = %s (%s), remote %q", e.Root, e.Kind, e.Resolution.Remote)
+			}
+			if e.Resolution.Source != tt.wantFrom {
+				t.Errorf("resolved from %q, want %q", e.Resolution.Source, tt.wantFrom)
+			}
+			got := *e
+			got.Root, got.WorkTree, got.GitDir, got.CommonDir, got.Kind, got.Resolution = "", "", "", "", "", nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Explain() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	useConfig(t, contexts+"defaults:\n  context: nobody\n")
+	t.Setenv(config.EnvContext, "work")
+	if e := Explain(r); e.Resolution.Source != config.SourceOverride || e.Resolution.ContextName != "work" {
+		t.Errorf("Explain() with %s set resolved %q from %q, want the override", config.EnvContext, e.Resolution.ContextName, e.Resolution.Source)
+	}
+	t.Setenv(config.EnvContext, "")
+
+useConfig(t, "version: 1\ndefaults:\n  context: ghost\npolicies:\n  requireContext: true\n")
+	if e := Explain(r); !e.ContextMissing || !e.Refused || e.TokenSource != "" {
+		t.Errorf("Explain() with an undefined context = %+v, want it missing and refused", e)
+	}
+}
@@ -6,11 +6,10 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/riad804/github-auth-manager/internal/config"
-	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/tokensource"
 
 	"github.com/go-git/go-git/v5"
 	gc "github.com/go-git/go-git/v5/config"
@@ -21,43 +20,6 @@ import (
 // var activeContext *Context
 // var token string
 
-// FindRepoRoot traverses up from the given path to find a .git directory.
-// Returns the absolute path to the directory containing .git, or an error if not found.
-func FindRepoRoot(startPath string) (string, error) {
-	currentPath, err := filepath.Abs(startPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path for '%s': %w", startPath, err)
-	}
-	fi, err := os.Stat(currentPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("path '%s' does not exist", startPath)
-		}
-		return "", fmt.Errorf("failed to stat path '%s': %w", startPath, err)
-	}
-	if !fi.IsDir() {
-		currentPath = filepath.Dir(currentPath)
-	}
-	originalPathForError := currentPath
-
-	for {
-		gitDirPath := filepath.Join(currentPath, ".git")
-		stat, err := os.Stat(gitDirPath)
-		if err == nil && stat.IsDir() {
-			return currentPath, nil
-		}
-		if err == nil && !stat.IsDir() {
-			return currentPath, nil
-		}
-		parent := filepath.Dir(currentPath)
-		if parent == currentPath {
-			break
-		}
-		currentPath = parent
-	}
-	return "", fmt.Errorf("not a git repository (or any of the parent directories of '%s')", originalPathForError)
-}
-
 // getHostFromURL parses a git remote URL and returns the host.
 // e.g., https://github.com/user/repo.git -> github.com
 //
@@ -88,7 +50,9 @@ func getHostFromURL(remoteURL string) (string, error) {
 
 // ExecuteGitCommandWithContext wraps a git command, injecting context-specific credentials.
 // Takes io.Writer for stdout and stderr for better testability and control.
-func ExecuteGitCommandWithContext(gitArgs []string, outW, errW io.Writer) error {
+// contextName, if set, is used instead of the repository's context (see
+// ContextOverride).
+func ExecuteGitCommandWithContext(gitArgs []string, contextName string, outW, errW io.Writer) error {
 	if len(gitArgs) == 0 {
 		return fmt.Errorf("no git command provided")
 	}
@@ -97,42 +61,96 @@ func ExecuteGitCommandWithContext(gitArgs []string, outW, errW io.Writer) error
 	if err != nil {
 		return fmt.Errorf("failed to get current working directory: %w", err)
 	}
-	command := gitArgs[0]
+	// Global options such as -C or --git-dir decide which repository (and so
+	// which context) the command applies to.
+	globals, gitArgs, err := ParseGlobalOptions(gitArgs, cwd)
+	if err != nil {
+		return err
+	}
 	var activeContext *config.Context
 	var token string
-	var contextName string
+	overrideName, overrideOrigin := ContextOverride(contextName)
+	contextName = ""
 
-	repoRoot, err := FindRepoRoot(cwd)
+	repo, err := globals.Repo()
 	isInsideRepo := err == nil
+	if !isInsideRepo {
+		repo = nil
+	}
 
-	if isInsideRepo {
-		repoCtxName, found := config.GetRepoContextName(repoRoot)
-		if found {
-			ctx, ctxFound := config.FindContext(repoCtxName)
-			if ctxFound {
-				activeContext = ctx
-				contextName = ctx.Name
-				retrievedToken, tokenErr := keyring.GetToken(ctx.Name)
-				if tokenErr != nil {
-					fmt.Fprintf(errW, "Warning: GHAM context '%s' is active but token could not be retrieved: %v\n", ctx.Name, tokenErr)
-					fmt.Fprintln(errW, "Git command will proceed without GHAM token injection.")
-				} else {
-					token = retrievedToken
-				}
+	// Aliases are expanded first so 'gham git p' routes and authenticates like
+	// 'gham git push'. Options in an alias can point git at another repository.
+	before := *globals
+	gitArgs, shellAlias, err := expandAliases(globals, repo, gitArgs)
+	if err != nil {
+		return err
+	}
+	if globals.Dir != before.Dir || globals.GitDir != before.GitDir || globals.WorkTree != before.WorkTree || globals.Bare != before.Bare {
+		repo, err = globals.Repo()
+		isInsideRepo = err == nil
+	}
+	command := ""
+	if len(gitArgs) > 0 && !shellAlias {
+		command = gitArgs[0]
+	}
+
+	var repoCtxName string
+	var found bool
+	if overrideName != "" {
+		// A one-off override wins over whatever the repository resolves to.
+		if _, ok := config.FindContext(overrideName); !ok {
+			return fmt.Errorf("context '%s' (from %s) not found", overrideName, overrideOrigin)
+		}
+		repoCtxName, found = overrideName, true
+	} else if isInsideRepo {
+		repoCtxName, found = config.GetRepoContextName(repo.Root)
+	} else if command == "clone" {
+		// Nothing can be assigned to a repository that does not exist yet, so
+		// the default for the URL's host applies, as it will after cloning.
+		host, _ := getHostFromURL(cloneURL(gitArgs))
+		repoCtxName, _, found = config.DefaultContextName(host)
+	}
+	if found {
+		ctx, ctxFound := config.FindContext(repoCtxName)
+		if ctxFound {
+			activeContext = ctx
+			contextName = ctx.Name
+			retrievedToken, tokenErr := tokensource.Resolve(ctx)
+			if tokenErr != nil {
+				fmt.Fprintf(errW, "Warning: GHAM context '%s' is active but token could not be retrieved: %v\n", ctx.Name, tokenErr)
+				fmt.Fprintln(errW, "Git command will proceed without GHAM token injection.")
 			} else {
-				fmt.Fprintf(errW, "Warning: Context '%s' assigned to repo but not found. Using system Git config.\n", repoCtxName)
+				token = retrievedToken
 			}
+		} else {
+			fmt.Fprintf(errW, "Warning: Context '%s' assigned to repo but not found. Using system Git config.\n", repoCtxName)
+		}
+	}
+
+	if isInsideRepo && activeContext == nil && config.Current().Config.Policies.RequireContext {
+		layer, file := config.Origin("policies.requireContext")
+		return fmt.Errorf("no GHAM context is assigned to '%s', and the %s config '%s' requires one (policies.requireContext). Use 'gham repo assign <context>'", repo.Root, layer, file)
+	}
+
+	if isInsideRepo {
+		if err := enforceProject(repo, activeContext, token, command, errW); err != nil {
+			return err
 		}
 	}
 
-	if isInsideRepo && activeContext != nil && token != "" {
+	// go-git cannot apply options like -c, so those commands go through git
+	// itself. The go-git handlers only talk to origin; other remotes (e.g.
+	// 'fetch fork') go through git too.
+	remote := commandRemote(gitArgs)
+	viaGoGit := !globals.Other && (remote == "" || remote == "origin") && !fetchesSeveral(gitArgs)
+	if isInsideRepo && activeContext != nil && token != "" && viaGoGit {
 		switch command {
 		case "pull":
-			return handlePullWithGoGit(repoRoot, gitArgs, activeContext, token, outW, errW)
+			return handlePullWithGoGit(repo, gitArgs, activeContext, token, outW, errW)
 		case "push":
-			return handlePushWithGoGit(repoRoot, gitArgs, activeContext, token, outW, errW)
+			return handlePushWithGoGit(repo, gitArgs, activeContext, token, outW, errW)
 		case "fetch":
-			return handleFetchWithGoGit(repoRoot, gitArgs, activeContext, token, outW, errW)
+			return handleFetchWithGoGit(repo, gitArgs, activeContext, token, outW, errW)
 		}
 	}
 
@@ -141,46 +159,29 @@ func ExecuteGitCommandWithContext(gitArgs []string, outW, errW io.Writer) error
 	}
 
 	// For other commands (including clone), use the original exec-based approach
-	return executeWithOSCommand(gitArgs, cwd, repoRoot, isInsideRepo, outW, errW, activeContext, token)
+	return executeWithOSCommand(globals, gitArgs, cwd, repo, outW, errW, activeContext, token)
 }
 
 // executeWithOSCommand handles non go-git commands using OS exec
-func executeWithOSCommand(gitArgs []string, cwd, repoRoot string, isInsideRepo bool, outW, errW io.Writer, activeContext *config.Context, token string) error {
+func executeWithOSCommand(globals *GlobalOptions, gitArgs []string, cwd string, repo *Repo, outW, errW io.Writer, activeContext *config.Context, token string) error {
 	cmdArgs := []string{}
 	envVars := os.Environ()
 
 	if activeContext != nil && token != "" {
-		// Disable credential helpers
-		cmdArgs = append(cmdArgs, "-c", "credential.helper=")
-
-		// Set user config if provided
-		if activeContext.Username != "" && activeContext.Username != config.DefaultUserName {
-			cmdArgs = append(cmdArgs, "-c", fmt.Sprintf("user.name=%s", activeContext.Username))
-		}
-		if activeContext.Email != "" {
-			cmdArgs = append(cmdArgs, "-c", fmt.Sprintf("user.email=%s", activeContext.Email))
+		for _, setting := range contextSettings(credentialHost(repo, gitArgs), activeContext) {
+			cmdArgs = append(cmdArgs, "-c", setting)
 		}
+		envVars = append(envVars, envHelperUsername+"="+helperUsername(activeContext), envHelperToken+"="+token)
 
-		// Handle clone command separately
-		if len(gitArgs) > 1 && gitArgs[0] == "clone" {
-			origURL := gitArgs[1]
-			// Convert SSH URLs to HTTPS
-			if strings.HasPrefix(origURL, "git@") {
-				origURL = convertSSHtoHTTPS(origURL)
-			}
-			if strings.HasPrefix(origURL, "https://") {
-				u, err := url.Parse(origURL)
-				if err != nil {
-					fmt.Fprintf(errW, "Warning: failed to parse URL %s: %v\n", origURL, err)
-				} else {
-					u.User = url.UserPassword(activeContext.Username, token)
-					gitArgs[1] = u.String()
-					fmt.Fprintf(errW, "[GHAM] Using authenticated URL: %s\n", u.Redacted())
-				}
-			}
+		// Clone SSH URLs over HTTPS so the credential helper answers for them. The
+		// token stays out of the URL, which git would save in .git/config.
+		if i := cloneURLIndex(gitArgs); i >= 0 && gitArgs[0] == "clone" && strings.HasPrefix(gitArgs[i], "git@") {
+			gitArgs[i] = convertSSHtoHTTPS(gitArgs[i])
 		}
 	}
 
+	// The user's global options come after gham's -c settings so theirs win.
+	cmdArgs = append(cmdArgs, globals.Args...)
 	cmdArgs = append(cmdArgs, gitArgs...)
 	gitCommand := exec.Command("git", cmdArgs...)
 	gitCommand.Env = envVars
@@ -188,17 +189,115 @@ func executeWithOSCommand(gitArgs []string, cwd, repoRoot string, isInsideRepo b
 	gitCommand.Stdout = outW
 	gitCommand.Stderr = errW
 
-	if isInsideRepo {
-		gitCommand.Dir = repoRoot
-	} else {
-		gitCommand.Dir = cwd
-	}
+	// git applies -C and resolves the repository (and GIT_DIR/GIT_WORK_TREE)
+	// itself; running from cwd keeps relative pathspecs working.
+	gitCommand.Dir = cwd
 
 	return gitCommand.Run()
 }
 
-func handlePullWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
-	repo, err := git.PlainOpen(repoRoot)
+// The credential helper reads the token from the environment of the git
+// process rather than its command line, which other users can see.
+const (
+	envHelperUsername = "GHAM_GIT_USERNAME"
+	envHelperToken    = "GHAM_GIT_TOKEN"
+	credentialHelper  = `!f() { test "$1" = get && printf 'username=%s\npassword=%s\n' "$GHAM_GIT_USERNAME" "$GHAM_GIT_TOKEN"; }; f`
+)
+
+// ContextOverride returns the context named by flagValue (the --gham-context
+// flag), or else by GHAM_CONTEXT, and which of the two named it.
+func ContextOverride(flagValue string) (name, origin string) {
+	if name := strings.TrimSpace(flagValue); name != "" {
+		return name, "--gham-context"
+	}
+	if name := strings.TrimSpace(os.Getenv(config.EnvContext)); name != "" {
+		return name, config.EnvContext
+	}
+	return "", ""
+}
+
+// contextSettings are the -c settings added to git commands run with ctx. They
+// replace the user's credential helpers with one answering from the
+// environment, for host only (none if host is empty); git passes -c settings
+// on to the git commands a shell alias runs, so those authenticate too.
+func contextSettings(host string, ctx *config.Context) []string {
+	var settings []string
+	if host != "" {
+		settings = append(settings,
+			"credential.helper=",
+			fmt.Sprintf("credential.https://%s.helper=%s", host, credentialHelper))
+	}
+	if ctx.Username != "" && ctx.Username != config.DefaultUserName {
+		settings = append(settings, "user.name="+ctx.Username)
+	}
+	if ctx.Email != "" {
+		settings = append(settings, "user.email="+ctx.Email)
+	}
+	return settings
+}
+
+// helperUsername is the username the credential helper answers with.
+func helperUsername(ctx *config.Context) string {
+	if ctx.Username == "" || ctx.Username == config.DefaultUserName {
+		return "x-access-token"
+	}
+	return ctx.Username
+}
+
+// cloneURL returns the repository argument of a clone command, or "".
+func cloneURL(gitArgs []string) string {
+	if i := cloneURLIndex(gitArgs); i >= 0 {
+		return gitArgs[i]
+	}
+	return ""
+}
+
+// cloneURLIndex returns the index of the repository argument of a clone
+// command in gitArgs, or -1.
+func cloneURLIndex(gitArgs []string) int {
+	for i := 1; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		switch {
+		case arg == "--":
+			if i+1 < len(gitArgs) {
+				return i + 1
+			}
+			return -1
+		case cloneValueOptions[arg]:
+			i++ // The option's value is the next argument.
+		case !strings.HasPrefix(arg, "-"):
+			return i
+		}
+	}
+	return -1
+}
+
+// cloneValueOptions are the options of git clone that take a separate value.
+var cloneValueOptions = map[string]bool{
+	"-b": true, "--branch": true, "-o": true, "--origin": true, "-u": true, "--upload-pack": true,
+	"-c": true, "--config": true, "-j": true, "--jobs": true, "--depth": true, "--reference": true,
+	"--reference-if-able": true, "--template": true, "--separate-git-dir": true, "--filter": true,
+	"--shallow-since": true, "--shallow-exclude": true, "--server-option": true, "--bundle-uri": true,
+}
+
+// credentialHost returns the host a command's credentials are for: that of
+// the clone URL, of the remote the command names, or else of the origin
+// remote. It is "" when there is none, e.g. for a remote on a local path.
+func credentialHost(repo *Repo, gitArgs []string) string {
+	if len(gitArgs) > 0 && gitArgs[0] == "clone" {
+		return remoteHost(nil, cloneURL(gitArgs))
+	}
+	if remote := commandRemote(gitArgs); remote != "" {
+		return remoteHost(repo, remote)
+	}
+	if repo == nil {
+		return ""
+	}
+	return remoteHost(repo, "origin")
+}
+
+func handlePullWithGoGit(r *Repo, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
+	repo, err := r.Open()
 	if err != nil {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -235,8 +334,8 @@ func handlePullWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context,
 	return w.Pull(opts)
 }
 
-func handlePushWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
-	repo, err := git.PlainOpen(repoRoot)
+func handlePushWithGoGit(r *Repo, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
+	repo, err := r.Open()
 	if err != nil {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -269,8 +368,8 @@ func handlePushWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context,
 	return repo.Push(opts)
 }
 
-func handleFetchWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
-	repo, err := git.PlainOpen(repoRoot)
+func handleFetchWithGoGit(r *Repo, gitArgs []string, ctx *config.Context, token string, outW, errW io.Writer) error {
+	repo, err := r.Open()
 	if err != nil {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
@@ -290,6 +389,19 @@ func handleFetchWithGoGit(repoRoot string, gitArgs []string, ctx *config.Context
 	return repo.Fetch(opts)
 }
 
+// ContextAuth returns HTTP credentials for go-git operations made on behalf of
+// ctx, resolving its token from the context's token source.
+func ContextAuth(ctx *config.Context) (*http.BasicAuth, error) {
+	token, err := tokensource.Resolve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token for context '%s': %w", ctx.Name, err)
+	}
+	return &http.BasicAuth{
+		Username: ctx.Username,
+		Password: token,
+	}, nil
+}
+
 // Helper functions
 func convertSSHtoHTTPS(sshURL string) string {
 	return strings.NewReplacer(
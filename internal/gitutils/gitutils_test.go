@@ -0,0 +1,82 @@
+package gitutils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func TestCloneKeepsTokenOutOfURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub git is a shell script")
+	}
+	// A stub git recording its arguments and the token it was given.
+	dir := t.TempDir()
+	stub := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$STUB_DIR/args\"\nprintf '%s' \"$" + envHelperToken + "\" > \"$STUB_DIR/token\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "git"), []byte(stub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("STUB_DIR", dir)
+
+	ctx := &config.Context{Name: "work", Username: "me"}
+	var out, errOut bytes.Buffer
+	gitArgs := []string{"clone", "--depth", "1", "git@github.com:acme/api.git"}
+	if err := executeWithOSCommand(&GlobalOptions{}, gitArgs, dir, nil, &out, &errOut, ctx, "ghp_secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(args), "ghp_secret") || strings.Contains(errOut.String(), "ghp_secret") {
+		t.Errorf("the token reached git's command line or output:\n%s%s", args, errOut.String())
+	}
+	if !strings.HasSuffix(string(args), "\nclone\n--depth\n1\nhttps://github.com/acme/api.git\n") {
+		t.Errorf("git args =\n%s\nwant the clone over HTTPS without credentials", args)
+	}
+	if !strings.Contains(string(args), "credential.https://github.com.helper=") {
+		t.Errorf("git args =\n%s\nwant the credential helper for github.com", args)
+	}
+	if token, _ := os.ReadFile(filepath.Join(dir, "token")); string(token) != "ghp_secret" {
+		t.Errorf("%s = %q, want the token for the credential helper", envHelperToken, token)
+	}
+}
+
+func TestContextOverride(t *testing.T) {
+	tests := []struct {
+		flag, env  string
+		want, from string
+	}{
+		{flag: " work ", env: "personal", want: "work", from: "--gham-context"},
+		{env: " personal\n", want: "personal", from: config.EnvContext},
+		{flag: "  ", env: "personal", want: "personal", from: config.EnvContext},
+		{},
+	}
+	for _, tt := range tests {
+		t.Setenv(config.EnvContext, tt.env)
+		if name, from := ContextOverride(tt.flag); name != tt.want || from != tt.from {
+			t.Errorf("ContextOverride(%q) with %s=%q = %q, %q, want %q, %q", tt.flag, config.EnvContext, tt.env, name, from, tt.want, tt.from)
+		}
+	}
+}
+
+func TestUnknownContextOverride(t *testing.T) {
+	useConfig(t, "version: 1\ncontexts:\n  - name: work\n")
+	t.Chdir(t.TempDir())
+	t.Setenv(config.EnvContext, "ghost")
+	err := ExecuteGitCommandWithContext([]string{"status"}, "", &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "context 'ghost' (from "+config.EnvContext+") not found") {
+		t.Errorf("ExecuteGitCommandWithContext() with an unknown override = %v", err)
+	}
+	err = ExecuteGitCommandWithContext([]string{"status"}, "nobody", &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "context 'nobody' (from --gham-context) not found") {
+		t.Errorf("ExecuteGitCommandWithContext() with an unknown --gham-context = %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package gitutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GlobalOptions are the options given to git before the subcommand
+// (git [<options>] <command> [<args>]).
+type GlobalOptions struct {
+	Args     []string // the options as given, passed through to git verbatim
+	Dir      string   // the directory git runs in after applying -C
+	GitDir   string   // --git-dir, relative to Dir
+	WorkTree string   // --work-tree, relative to Dir
+	Bare     bool     // --bare
+	Config   []string // -c name=value
+	// Other is set when an option go-git cannot honor is present (e.g. -c or
+	// --namespace), so the command must go through the git executable.
+	Other bool
+}
+
+// globalOptionsWithValue take their value as the next argument when not given as '--opt=value'.
+var globalOptionsWithValue = map[string]bool{
+	"-C":             true,
+	"-c":             true,
+	"--git-dir":      true,
+	"--work-tree":    true,
+	"--namespace":    true,
+	"--config-env":   true,
+	"--super-prefix": true,
+	"--attr-source":  true,
+}
+
+// globalOptionsHarmless change nothing go-git would do differently.
+var globalOptionsHarmless = map[string]bool{
+	"-p":                  true,
+	"--paginate":          true,
+	"-P":                  true,
+	"--no-pager":          true,
+	"--no-optional-locks": true,
+	"--no-advice":         true,
+}
+
+// ParseGlobalOptions splits git arguments into the global options and the
+// subcommand with its arguments. cwd is where 'gham git' was started.
+func ParseGlobalOptions(args []string, cwd string) (*GlobalOptions, []string, error) {
+	g := &GlobalOptions{Dir: cwd}
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") {
+			// Short options: '-C path', '-Cpath', '-c k=v', '-ck=v'.
+			name, value, hasValue = arg[:2], arg[2:], len(arg) > 2
+		}
+		consumed := 1
+		if globalOptionsWithValue[name] && !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("git option %s requires a value", name)
+			}
+			value, consumed = args[i+1], 2
+		}
+
+		switch name {
+		case "-C":
+			if value != "" {
+				g.Dir = absFrom(g.Dir, value)
+			}
+		case "--git-dir":
+			g.GitDir = absFrom(g.Dir, value)
+		case "--work-tree":
+			g.WorkTree = absFrom(g.Dir, value)
+		case "--bare":
+			g.Bare = true
+		case "-c":
+			g.Config = append(g.Config, value)
+			g.Other = true
+		case "--exec-path":
+			g.Other = true
+			if hasValue {
+				break
+			}
+			// Without a value it prints the path, like the options below.
+			fallthrough
+		case "--version", "--help", "-h", "--html-path", "--man-path", "--info-path", "--list-cmds":
+			// Options that are really commands: git runs them and ignores the rest.
+			g.Other = true
+			g.Args = append(g.Args, args[i:i+consumed]...)
+			return g, args[i+consumed:], nil
+		default:
+			if !globalOptionsHarmless[name] {
+				g.Other = true
+			}
+		}
+		g.Args = append(g.Args, args[i:i+consumed]...)
+		i += consumed
+	}
+	if g.Dir != cwd {
+		if fi, err := os.Stat(g.Dir); err != nil || !fi.IsDir() {
+			return nil, nil, fmt.Errorf("cannot change to '%s': no such directory", g.Dir)
+		}
+	}
+	return g, args[i:], nil
+}
+
+// Repo locates the repository git will operate on with these options,
+// falling back to GIT_DIR/GIT_WORK_TREE and discovery from Dir like git does.
+func (g *GlobalOptions) Repo() (*Repo, error) {
+	gitDir, workTree := g.GitDir, g.WorkTree
+	if gitDir == "" {
+		if env := os.Getenv("GIT_DIR"); env != "" {
+			gitDir = absFrom(g.Dir, env)
+		} else if g.Bare {
+			gitDir = g.Dir
+		}
+	}
+	if workTree == "" && os.Getenv("GIT_WORK_TREE") != "" {
+		workTree = absFrom(g.Dir, os.Getenv("GIT_WORK_TREE"))
+	}
+	if gitDir == "" {
+		if workTree != "" {
+			// Only the working tree is given: the repository is still found from Dir.
+			r, err := FindRepo(g.Dir)
+			if err != nil {
+				return nil, err
+			}
+			r.WorkTree = filepath.Clean(workTree)
+			return r, nil
+		}
+		return FindRepo(g.Dir)
+	}
+	return repoAt(g.Dir, gitDir, workTree, g.Bare)
+}
@@ -0,0 +1,112 @@
+package gitutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalOptions(t *testing.T) {
+	cwd := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(cwd, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	at := func(p string) string { return filepath.Join(cwd, p) }
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     GlobalOptions // Args is checked as given
+		wantRest []string
+		wantErr  bool
+	}{
+		{
+			name:     "no options",
+			args:     []string{"push", "-f"},
+			want:     GlobalOptions{Dir: cwd},
+			wantRest: []string{"push", "-f"},
+		},
+		{
+			name:     "stacked -C",
+			args:     []string{"-C", "a", "-C", "b", "status"},
+			want:     GlobalOptions{Args: []string{"-C", "a", "-C", "b"}, Dir: at("a/b")},
+			wantRest: []string{"status"},
+		},
+		{
+			name:     "-C attached and empty",
+			args:     []string{"-Ca", "-C", "", "status"},
+			want:     GlobalOptions{Args: []string{"-Ca", "-C", ""}, Dir: at("a")},
+			wantRest: []string{"status"},
+		},
+		{
+			name:     "--opt=value",
+			args:     []string{"-C", "a", "--git-dir=../repo.git", "--work-tree=b", "log"},
+			want:     GlobalOptions{Args: []string{"-C", "a", "--git-dir=../repo.git", "--work-tree=b"}, Dir: at("a"), GitDir: at("repo.git"), WorkTree: at("a/b")},
+			wantRest: []string{"log"},
+		},
+		{
+			name:     "--opt value",
+			args:     []string{"--git-dir", "repo.git", "--work-tree", "a", "log"},
+			want:     GlobalOptions{Args: []string{"--git-dir", "repo.git", "--work-tree", "a"}, Dir: cwd, GitDir: at("repo.git"), WorkTree: at("a")},
+			wantRest: []string{"log"},
+		},
+		{
+			name:     "-c",
+			args:     []string{"-c", "user.name=Me", "-cuser.email=me@example.com", "commit"},
+			want:     GlobalOptions{Args: []string{"-c", "user.name=Me", "-cuser.email=me@example.com"}, Dir: cwd, Config: []string{"user.name=Me", "user.email=me@example.com"}, Other: true},
+			wantRest: []string{"commit"},
+		},
+		{
+			name:    "-c without a value",
+			args:    []string{"-c"},
+			wantErr: true,
+		},
+		{
+			name:     "--bare and harmless options",
+			args:     []string{"--no-pager", "--bare", "-P", "log"},
+			want:     GlobalOptions{Args: []string{"--no-pager", "--bare", "-P"}, Dir: cwd, Bare: true},
+			wantRest: []string{"log"},
+		},
+		{
+			name:     "-- ends the options",
+			args:     []string{"-C", "a", "--", "-C", "b"},
+			want:     GlobalOptions{Args: []string{"-C", "a"}, Dir: at("a")},
+			wantRest: []string{"--", "-C", "b"},
+		},
+		{
+			name:     "unknown option",
+			args:     []string{"--literal-pathspecs", "add", "*.go"},
+			want:     GlobalOptions{Args: []string{"--literal-pathspecs"}, Dir: cwd, Other: true},
+			wantRest: []string{"add", "*.go"},
+		},
+		{
+			name:     "option that is a command",
+			args:     []string{"--version", "--build-options"},
+			want:     GlobalOptions{Args: []string{"--version"}, Dir: cwd, Other: true},
+			wantRest: []string{"--build-options"},
+		},
+		{
+			name:    "missing -C directory",
+			args:    []string{"-C", "nope", "status"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := ParseGlobalOptions(tt.args, cwd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGlobalOptions() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("options = %+v, want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package gitutils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/project"
+)
+
+// CheckProject checks ctx (nil if none) against the .gham.yaml of repo. It
+// returns nil requirements if the repository has no project file. The token's
+// scopes are only checked when token is set.
+func CheckProject(repo *Repo, ctx *config.Context, token string) (*project.Requirements, []project.Violation, error) {
+	req, err := project.Load(repo.WorkTree)
+	if err != nil || req == nil {
+		return nil, nil, err
+	}
+	signing, _ := ConfigValue(repo, "commit", "gpgsign")
+	checkout := project.Checkout{
+		Remote:       config.RemoteIdentity(repo.Root),
+		SignsCommits: isTrue(signing),
+	}
+	if ctx != nil {
+		checkout.TokenHosts = config.TokenHosts(ctx.Name)
+	}
+	return req, req.Check(ctx, checkout, token), nil
+}
+
+// enforceProject reports project requirement violations on errW and returns an
+// error if the project file refuses commands that violate them.
+func enforceProject(repo *Repo, ctx *config.Context, token, command string, errW io.Writer) error {
+	// Asking the API for the token's scopes is only worth it for network commands.
+	switch command {
+	case "push", "pull", "fetch", "clone", "ls-remote":
+	default:
+		token = ""
+	}
+	req, violations, err := CheckProject(repo, ctx, token)
+	if err != nil {
+		return err
+	}
+	refused := 0
+	for _, v := range violations {
+		// 'git config' stays usable so the checkout can be fixed, e.g. to enable signing.
+		if req.Refuse() && !v.Unverified && command != "config" {
+			refused++
+			fmt.Fprintf(errW, "[GHAM] Error: %s\n", v.Message)
+		} else {
+			fmt.Fprintf(errW, "[GHAM] Warning: %s\n", v.Message)
+		}
+	}
+	if refused > 0 {
+		return fmt.Errorf("%d requirement(s) of %s are not met; fix them or use 'gham repo assign' to pick a matching context", refused, req.Path())
+	}
+	return nil
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
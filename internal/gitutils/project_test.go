@@ -0,0 +1,79 @@
+package gitutils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/project"
+)
+
+func TestEnforceProject(t *testing.T) {
+	useGitConfig(t, "")
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{"https://github.com/acme/api.git"}}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := FindRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := &config.Context{Name: "work", Email: "me@acme.example"}
+
+	tests := []struct {
+		name        string
+		projectFile string // "" for none
+		signing     bool   // commit.gpgsign in the repository
+		command     string
+		wantErr     bool
+		wantOutput  string // "" for no output
+	}{
+		{name: "no project file", command: "commit"},
+		{name: "met", projectFile: "owner: acme\nemailDomain: acme.example\nenforce: refuse\n", command: "push"},
+		{name: "warn", projectFile: "owner: other\n", command: "push", wantOutput: "[GHAM] Warning: origin remote"},
+		{name: "refuse", projectFile: "owner: other\nenforce: refuse\n", command: "push", wantErr: true, wantOutput: "[GHAM] Error: origin remote"},
+		{name: "config stays usable", projectFile: "signCommits: true\nenforce: refuse\n", command: "config", wantOutput: "[GHAM] Warning: commits must be signed"},
+		{name: "signing enabled", projectFile: "signCommits: true\nenforce: refuse\n", signing: true, command: "commit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, project.FileName)
+			os.Remove(path)
+			if tt.projectFile != "" {
+				if err := os.WriteFile(path, []byte(tt.projectFile), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg, err := repo.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.signing {
+				cfg.Raw.Section("commit").SetOption("gpgsign", "true")
+			} else {
+				cfg.Raw.RemoveSection("commit")
+			}
+			if err := repo.SetConfig(cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			err = enforceProject(r, work, "", tt.command, &out)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("enforceProject() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantOutput == "" && out.Len() > 0 || !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOutput)
+			}
+		})
+	}
+}
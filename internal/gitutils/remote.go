@@ -0,0 +1,85 @@
+package gitutils
+
+import "strings"
+
+// remoteCommands are the commands that take a remote as their first argument.
+var remoteCommands = map[string]bool{"fetch": true, "pull": true, "push": true, "ls-remote": true}
+
+// remoteValueOptions are the options of remoteCommands that take a separate value.
+var remoteValueOptions = map[string]bool{
+	"--depth": true, "--deepen": true, "--shallow-since": true, "--shallow-exclude": true,
+	"--refmap": true, "-o": true, "--server-option": true, "--push-option": true,
+	"--upload-pack": true, "--receive-pack": true, "--exec": true, "-j": true, "--jobs": true,
+	"--negotiation-tip": true, "--filter": true, "-s": true, "--strategy": true,
+	"-X": true, "--strategy-option": true, "--sort": true, "--repo": true,
+}
+
+// commandRemote returns the remote a fetch, pull, push or ls-remote command
+// names (a remote name or a URL), or "" if it names none and git picks one.
+func commandRemote(gitArgs []string) string {
+	if len(gitArgs) == 0 || !remoteCommands[gitArgs[0]] {
+		return ""
+	}
+	for i := 1; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--":
+			if i+1 < len(gitArgs) {
+				return gitArgs[i+1]
+			}
+			return ""
+		case name == "--repo": // push --repo=<remote>
+			if hasValue {
+				return value
+			}
+			if i+1 < len(gitArgs) {
+				return gitArgs[i+1]
+			}
+			return ""
+		case remoteValueOptions[arg]:
+			i++ // The option's value is the next argument.
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
+// fetchesSeveral reports whether a fetch or pull goes to more than one remote
+// (--all, --multiple), which the go-git handlers do not support.
+func fetchesSeveral(gitArgs []string) bool {
+	for _, arg := range gitArgs[1:] {
+		if arg == "--" {
+			break
+		}
+		if arg == "--all" || arg == "--multiple" {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the host of remote, a remote name of repo or a URL, or
+// "" if it cannot be determined (e.g. a local path).
+func remoteHost(repo *Repo, remote string) string {
+	if repo != nil && !strings.Contains(remote, ":") && !strings.Contains(remote, "/") {
+		r, err := repo.Open()
+		if err != nil {
+			return ""
+		}
+		rc, err := r.Remote(remote)
+		if err != nil || len(rc.Config().URLs) == 0 {
+			return ""
+		}
+		remote = rc.Config().URLs[0]
+	}
+	if !strings.Contains(remote, "://") && !strings.Contains(remote, "@") {
+		return "" // A local path.
+	}
+	host, err := getHostFromURL(remote)
+	if err != nil {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package gitutils
+
+import "testing"
+
+func TestCommandRemote(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"fetch"}, ""},
+		{[]string{"fetch", "fork"}, "fork"},
+		{[]string{"fetch", "--depth", "1", "fork", "main"}, "fork"},
+		{[]string{"fetch", "--depth=1", "fork"}, "fork"},
+		{[]string{"pull", "-s", "ours", "upstream", "main"}, "upstream"},
+		{[]string{"push", "-o", "ci.skip", "origin", "main"}, "origin"},
+		{[]string{"push", "--repo=fork"}, "fork"},
+		{[]string{"push", "--repo", "fork", "main"}, "fork"},
+		{[]string{"push", "--", "fork"}, "fork"},
+		{[]string{"ls-remote", "https://github.com/o/r.git"}, "https://github.com/o/r.git"},
+		{[]string{"status", "fork"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commandRemote(tt.args); got != tt.want {
+			t.Errorf("commandRemote(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialHostWithoutRepo(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"clone", "--depth", "1", "https://ghe.example.com/o/r.git"}, "ghe.example.com"},
+		{[]string{"clone", "git@github.com:o/r.git"}, "github.com"},
+		{[]string{"clone", "../local"}, ""},
+		{[]string{"ls-remote", "https://gitlab.example.com/o/r"}, "gitlab.example.com"},
+		{[]string{"fetch", "fork"}, ""}, // a remote name needs a repository
+		{[]string{"status"}, ""},
+	}
+	for _, tt := range tests {
+		if got := credentialHost(nil, tt.args); got != tt.want {
+			t.Errorf("credentialHost(nil, %q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFetchesSeveral(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"fetch", "--all"}, true},
+		{[]string{"fetch", "--multiple", "a", "b"}, true},
+		{[]string{"fetch", "origin"}, false},
+		{[]string{"fetch", "--", "--all"}, false},
+	}
+	for _, tt := range tests {
+		if got := fetchesSeveral(tt.args); got != tt.want {
+			t.Errorf("fetchesSeveral(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package gitutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	gitconfig "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Kinds of repositories found by FindRepo.
+const (
+	KindRepository = "repository"
+	KindWorktree   = "worktree"  // a linked worktree ('git worktree add')
+	KindSubmodule  = "submodule" // a submodule checkout; resolved on its own
+	KindBare       = "bare"
+)
+
+// Repo describes where a repository's working tree and git directories are.
+type Repo struct {
+	// Root identifies the repository for context resolution: the main working
+	// tree, shared by all linked worktrees, or the git directory of a bare repository.
+	Root      string
+	WorkTree  string // the checkout in use; empty for a bare repository
+	GitDir    string // this checkout's git directory
+	CommonDir string // the git directory shared by all worktrees
+	Kind      string
+	FromEnv   bool // located through GIT_DIR or --git-dir
+}
+
+// FindRepo locates the repository containing startPath the way git does: a
+// '.git' directory or a '.git' file ("gitdir: ..."), or a bare git directory.
+func FindRepo(startPath string) (*Repo, error) {
+	currentPath, err := filepath.Abs(startPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for '%s': %w", startPath, err)
+	}
+	fi, err := os.Stat(currentPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("path '%s' does not exist", startPath)
+		}
+		return nil, fmt.Errorf("failed to stat path '%s': %w", startPath, err)
+	}
+	if !fi.IsDir() {
+		currentPath = filepath.Dir(currentPath)
+	}
+	originalPathForError := currentPath
+
+	for {
+		dotGit := filepath.Join(currentPath, ".git")
+		if stat, err := os.Stat(dotGit); err == nil {
+			if stat.IsDir() {
+				return repoFor(currentPath, dotGit)
+			}
+			gitDir, err := readGitFile(dotGit)
+			if err != nil {
+				return nil, err
+			}
+			return repoFor(currentPath, gitDir)
+		}
+		if isGitDir(currentPath) {
+			return repoFor("", currentPath)
+		}
+		parent := filepath.Dir(currentPath)
+		if parent == currentPath {
+			break
+		}
+		currentPath = parent
+	}
+	return nil, fmt.Errorf("not a git repository (or any of the parent directories of '%s')", originalPathForError)
+}
+
+// CurrentRepo locates the repository git would use in the current directory,
+// honoring GIT_DIR and GIT_WORK_TREE.
+func CurrentRepo() (*Repo, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	return (&GlobalOptions{Dir: cwd}).Repo()
+}
+
+// repoAt describes the repository at an explicitly given git directory.
+func repoAt(dir, gitDir, workTree string, bare bool) (*Repo, error) {
+	if !isGitDir(gitDir) {
+		return nil, fmt.Errorf("'%s' is not a git directory", gitDir)
+	}
+	if workTree == "" && !bare && !coreBare(gitDir) {
+		// Without a work tree, git uses core.worktree, then treats the
+		// current directory as the top of the working tree.
+		if workTree = coreWorktree(gitDir); workTree == "" {
+			workTree = dir
+		}
+	}
+	r, err := repoFor(workTree, gitDir)
+	if err != nil {
+		return nil, err
+	}
+	r.FromEnv = true
+	return r, nil
+}
+
+// FindRepoRoot traverses up from the given path to find the repository and
+// returns its Root: linked worktrees resolve to their main working tree.
+func FindRepoRoot(startPath string) (string, error) {
+	r, err := FindRepo(startPath)
+	if err != nil {
+		return "", err
+	}
+	return r.Root, nil
+}
+
+// repoFor completes a Repo from its working tree (empty if bare) and git directory.
+func repoFor(workTree, gitDir string) (*Repo, error) {
+	r := &Repo{WorkTree: workTree, GitDir: gitDir, CommonDir: gitDir, Kind: KindRepository}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		r.CommonDir = absFrom(gitDir, strings.TrimSpace(string(data)))
+		r.Kind = KindWorktree
+	} else if strings.Contains(filepath.ToSlash(gitDir), "/.git/modules/") {
+		r.Kind = KindSubmodule
+	}
+
+	switch {
+	case r.Kind == KindSubmodule:
+		r.Root = workTree
+		if r.Root == "" {
+			// Inside .git/modules/<name>: the checkout is in core.worktree.
+			r.Root, r.WorkTree = coreWorktree(gitDir), coreWorktree(gitDir)
+		}
+		if r.Root == "" {
+			r.Root = gitDir
+		}
+	case filepath.Base(r.CommonDir) == ".git":
+		r.Root = filepath.Dir(r.CommonDir)
+	case coreWorktree(r.CommonDir) != "":
+		// A submodule's worktree, or a git dir with core.worktree set.
+		r.Root = coreWorktree(r.CommonDir)
+	case workTree != "" && r.Kind == KindRepository:
+		// e.g. 'git init --separate-git-dir'.
+		r.Root = workTree
+	default:
+		r.Root = r.CommonDir
+	}
+	if workTree == "" && r.Kind == KindRepository {
+		if filepath.Base(gitDir) == ".git" {
+			// Inside the .git directory of a regular checkout.
+			r.WorkTree = r.Root
+		} else {
+			r.Kind = KindBare
+		}
+	}
+	return r, nil
+}
+
+// Open opens the repository with go-git.
+func (r *Repo) Open() (*git.Repository, error) {
+	if r.FromEnv || r.WorkTree == "" {
+		storage := filesystem.NewStorage(osfs.New(r.GitDir), cache.NewObjectLRUDefault())
+		if r.WorkTree == "" {
+			return git.Open(storage, nil)
+		}
+		return git.Open(storage, osfs.New(r.WorkTree))
+	}
+	return git.PlainOpenWithOptions(r.WorkTree, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// readGitFile returns the git directory a '.git' file points to.
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("invalid git file '%s': missing 'gitdir:'", path)
+	}
+	return absFrom(filepath.Dir(path), strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))), nil
+}
+
+// isGitDir reports whether dir looks like a git directory, as git checks it.
+func isGitDir(dir string) bool {
+	if fi, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || fi.IsDir() {
+		return false
+	}
+	common := dir
+	if data, err := os.ReadFile(filepath.Join(dir, "commondir")); err == nil {
+		common = absFrom(dir, strings.TrimSpace(string(data)))
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if fi, err := os.Stat(filepath.Join(common, sub)); err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// coreBare reads core.bare from a git directory's config.
+func coreBare(gitDir string) bool {
+	return readCoreOption(gitDir, "bare") == "true"
+}
+
+// coreWorktree reads core.worktree from a git directory's config, made absolute.
+func coreWorktree(gitDir string) string {
+	wt := readCoreOption(gitDir, "worktree")
+	if wt == "" {
+		return ""
+	}
+	return absFrom(gitDir, wt)
+}
+
+func readCoreOption(gitDir, key string) string {
+	value, _ := readConfigOption(filepath.Join(gitDir, "config"), "core", key)
+	return value
+}
+
+// readConfigOption reads section.key from a git config file.
+func readConfigOption(path, section, key string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	cfg := gitconfig.New()
+	if err := gitconfig.NewDecoder(f).Decode(cfg); err != nil {
+		return "", false
+	}
+	if !cfg.Section(section).HasOption(key) {
+		return "", false
+	}
+	return cfg.Section(section).Option(key), true
+}
+
+func absFrom(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
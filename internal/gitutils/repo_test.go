@@ -0,0 +1,135 @@
+package gitutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newRepoLayout lays out, under a temporary directory, the git directories
+// of a checkout with a linked worktree and a submodule, a bare repository and
+// a git directory separated from its working tree:
+//
+//	main/.git, main/.git/worktrees/wt, main/.git/modules/sub
+//	wt/.git -> main/.git/worktrees/wt
+//	main/sub/.git -> main/.git/modules/sub (core.worktree = main/sub)
+//	bare.git (core.bare)
+//	sep.git (core.worktree = sep)
+func newRepoLayout(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitDir := func(dir, config string) {
+		t.Helper()
+		write(filepath.Join(dir, "HEAD"), "ref: refs/heads/main\n")
+		write(filepath.Join(dir, "config"), config)
+		for _, sub := range []string{"objects", "refs"} {
+			if err := os.MkdirAll(filepath.Join(root, dir, sub), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	gitDir("main/.git", "[core]\n\tbare = false\n")
+	if err := os.MkdirAll(filepath.Join(root, "main", "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write("main/.git/worktrees/wt/HEAD", "ref: refs/heads/feature\n")
+	write("main/.git/worktrees/wt/commondir", "../..\n")
+	write("wt/.git", "gitdir: "+filepath.Join(root, "main/.git/worktrees/wt")+"\n")
+	gitDir("main/.git/modules/sub", "[core]\n\tworktree = ../../../sub\n")
+	write("main/sub/.git", "gitdir: ../.git/modules/sub\n")
+	gitDir("bare.git", "[core]\n\tbare = true\n")
+	gitDir("sep.git", "[core]\n\tworktree = ../sep\n")
+	if err := os.MkdirAll(filepath.Join(root, "sep"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestFindRepo(t *testing.T) {
+	root := newRepoLayout(t)
+	tests := []struct {
+		path                             string
+		wantKind, wantRoot, wantWorkTree string
+		wantGitDir, wantCommonDir        string
+	}{
+		{"main/src", KindRepository, "main", "main", "main/.git", "main/.git"},
+		{"main/.git/refs", KindRepository, "main", "main", "main/.git", "main/.git"},
+		{"wt", KindWorktree, "main", "wt", "main/.git/worktrees/wt", "main/.git"},
+		{"main/sub", KindSubmodule, "main/sub", "main/sub", "main/.git/modules/sub", "main/.git/modules/sub"},
+		{"main/.git/modules/sub", KindSubmodule, "main/sub", "main/sub", "main/.git/modules/sub", "main/.git/modules/sub"},
+		{"bare.git/refs", KindBare, "bare.git", "", "bare.git", "bare.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			r, err := FindRepo(filepath.Join(root, tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			abs := func(p string) string {
+				if p == "" {
+					return ""
+				}
+				return filepath.Join(root, p)
+			}
+			want := Repo{Root: abs(tt.wantRoot), WorkTree: abs(tt.wantWorkTree), GitDir: abs(tt.wantGitDir), CommonDir: abs(tt.wantCommonDir), Kind: tt.wantKind}
+			if *r != want {
+				t.Errorf("FindRepo() = %+v, want %+v", *r, want)
+			}
+		})
+	}
+
+	if _, err := FindRepo(t.TempDir()); err == nil {
+		t.Error("FindRepo() found a repository in an empty directory")
+	}
+}
+
+func TestRepoFromGitDir(t *testing.T) {
+	root := newRepoLayout(t)
+	tests := []struct {
+		name         string
+		dir          string // where git runs
+		gitDir       string // GIT_DIR
+		workTree     string // GIT_WORK_TREE
+		wantKind     string
+		wantRoot     string
+		wantWorkTree string
+	}{
+		{"current directory is the work tree", "main/src", "main/.git", "", KindRepository, "main", "main/src"},
+		{"GIT_WORK_TREE", "main/src", "main/.git", "main", KindRepository, "main", "main"},
+		{"core.worktree of a submodule", "bare.git", "main/.git/modules/sub", "", KindSubmodule, "main/sub", "main/sub"},
+		{"core.worktree of a separate git dir", "main", "sep.git", "", KindRepository, "sep", "sep"},
+		{"bare", "main", "bare.git", "", KindBare, "bare.git", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_DIR", filepath.Join(root, tt.gitDir))
+			if tt.workTree != "" {
+				t.Setenv("GIT_WORK_TREE", filepath.Join(root, tt.workTree))
+			} else {
+				t.Setenv("GIT_WORK_TREE", "")
+			}
+			r, err := (&GlobalOptions{Dir: filepath.Join(root, tt.dir)}).Repo()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantWorkTree := ""
+			if tt.wantWorkTree != "" {
+				wantWorkTree = filepath.Join(root, tt.wantWorkTree)
+			}
+			if r.Kind != tt.wantKind || r.Root != filepath.Join(root, tt.wantRoot) || r.WorkTree != wantWorkTree || !r.FromEnv {
+				t.Errorf("Repo() = %+v, want kind %s, root %s and work tree %s", *r, tt.wantKind, tt.wantRoot, tt.wantWorkTree)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"github.com/riad804/github-auth-manager/internal/bundle"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ghHost is one host entry of the gh CLI's hosts.yml.
+type ghHost struct {
+	User       string                   `yaml:"user"`
+	OAuthToken string                   `yaml:"oauth_token"`
+	Users      map[string]ghHostAccount `yaml:"users"`
+}
+
+type ghHostAccount struct {
+	OAuthToken string `yaml:"oauth_token"`
+}
+
+// Hosts and logins as gh accepts them. They end up in a 'gh auth token'
+// command run by the shell, so anything else in hosts.yml is refused.
+var (
+	ghHostPattern  = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]+)?$`)
+	ghLoginPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// ghHostsPath follows gh's own lookup: $GH_CONFIG_DIR, then $XDG_CONFIG_HOME/gh,
+// then %AppData%\GitHub CLI on Windows, then ~/.config/gh.
+func ghHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if appData := os.Getenv("AppData"); runtime.GOOS == "windows" && appData != "" {
+		return filepath.Join(appData, "GitHub CLI", "hosts.yml")
+	}
+	return filepath.Join(homeDir(), ".config", "gh", "hosts.yml")
+}
+
+// FromGH proposes a context for each account logged in with the gh CLI.
+// Tokens gh keeps in plain text are imported; tokens in gh's own keyring
+// become a command token source ('gh auth token'), read at use time.
+func FromGH(path string) (*bundle.Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+
+	hostNames := make([]string, 0, len(hosts))
+	for h := range hosts {
+		hostNames = append(hostNames, h)
+	}
+	sort.Strings(hostNames)
+
+	b := bundle.New()
+	b.Tokens = map[string]string{}
+	taken := map[string]bool{}
+	for _, host := range hostNames {
+		if !ghHostPattern.MatchString(host) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping host '%s' of '%s': not a valid host name.\n", host, path)
+			continue
+		}
+		entry := hosts[host]
+		accounts := map[string]string{}
+		for user, acct := range entry.Users {
+			accounts[user] = acct.OAuthToken
+		}
+		if entry.User != "" { // Older hosts.yml: a single account per host.
+			if _, ok := accounts[entry.User]; !ok || accounts[entry.User] == "" {
+				accounts[entry.User] = entry.OAuthToken
+			}
+		}
+		users := make([]string, 0, len(accounts))
+		for u := range accounts {
+			users = append(users, u)
+		}
+		sort.Strings(users)
+
+		for _, user := range users {
+			if !ghLoginPattern.MatchString(user) {
+				fmt.Fprintf(os.Stderr, "Warning: skipping account '%s' on %s of '%s': not a valid login.\n", user, host, path)
+				continue
+			}
+			hint := user
+			if host != "github.com" {
+				hint = user + "-" + host
+			}
+			name := uniqueName(contextName(hint), taken)
+			ctx := config.Context{Name: name, Username: user}
+			if token := accounts[user]; token != "" {
+				b.Tokens[name] = token
+			} else {
+				ctx.TokenSource = &config.TokenSource{
+					Type:    config.TokenSourceCommand,
+					Command: fmt.Sprintf("gh auth token --hostname %s --user %s", host, user),
+				}
+			}
+			b.Contexts = append(b.Contexts, ctx)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	gitconfig "github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/riad804/github-auth-manager/internal/bundle"
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// FromGitConfig proposes a context for each identity git selects with
+// [includeIf "gitdir:..."], plus a path rule for its directory, or with
+// [includeIf "hasconfig:remote.*.url:..."], plus an assignment for that
+// repository, and a "default" context for the top-level [user] identity.
+//
+//	[includeIf "gitdir:~/work/"]
+//	    path = ~/.gitconfig-work   ->  context "work", rule ~/work
+func FromGitConfig(path string) (*bundle.Bundle, error) {
+	cfg, err := readGitConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	b := bundle.New()
+	taken := map[string]bool{}
+
+	if user := cfg.Section("user"); user.Option("email") != "" || user.Option("name") != "" {
+		b.Contexts = append(b.Contexts, config.Context{
+			Name:     uniqueName("default", taken),
+			Username: user.Option("name"),
+			Email:    user.Option("email"),
+		})
+	}
+
+	for _, sub := range cfg.Section("includeIf").Subsections {
+		condition, pattern, ok := strings.Cut(sub.Name, ":")
+		var ruleDir, remote string
+		switch {
+		case ok && (condition == "gitdir" || condition == "gitdir/i"):
+			if ruleDir, err = gitdirRulePath(pattern, filepath.Dir(path), condition == "gitdir/i"); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping [includeIf \"%s\"]: %v\n", sub.Name, err)
+				continue
+			}
+		case ok && condition == "hasconfig" && strings.HasPrefix(pattern, "remote.*.url:"):
+			if remote, err = hasconfigRemote(strings.TrimPrefix(pattern, "remote.*.url:")); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping [includeIf \"%s\"]: %v\n", sub.Name, err)
+				continue
+			}
+		default:
+			continue // e.g. onbranch:, which gham cannot express
+		}
+		for _, includePath := range sub.OptionAll("path") {
+			included, err := readGitConfig(expandPath(includePath, filepath.Dir(path)))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping include '%s': %v\n", includePath, err)
+				continue
+			}
+			user := included.Section("user")
+			if user.Option("email") == "" && user.Option("name") == "" {
+				continue
+			}
+			hint := ruleDir
+			if remote != "" {
+				hint = remote
+			}
+			name := uniqueName(contextName(includeNameHint(includePath, hint)), taken)
+			b.Contexts = append(b.Contexts, config.Context{Name: name, Username: user.Option("name"), Email: user.Option("email")})
+			if remote != "" {
+				b.Repositories = append(b.Repositories, config.RepoConfig{Remote: remote, ContextName: name})
+			} else {
+				b.Rules = append(b.Rules, config.PathRule{Path: ruleDir, ContextName: name})
+			}
+		}
+	}
+	return b, nil
+}
+
+func readGitConfig(path string) (*gitconfig.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg := gitconfig.New()
+	if err := gitconfig.NewDecoder(f).Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	return cfg, nil
+}
+
+// gitdirRulePath converts the gitdir: (or, with ignoreCase, gitdir/i:) pattern
+// of the config file in configDir into a gham path rule. It follows git: "~/"
+// is the home directory, "./" the config file's directory, and a trailing "/"
+// or "/**" means "and everything below", which path rules imply. Patterns git
+// matches at any depth (relative ones, which git prefixes with "**/", or ones
+// containing "**") cannot be expressed as a path rule and return an error.
+func gitdirRulePath(pattern, configDir string, ignoreCase bool) (string, error) {
+	rule := pattern
+	if strings.HasPrefix(rule, "./") {
+		rule = filepath.ToSlash(configDir) + rule[1:]
+	}
+	if !strings.HasPrefix(rule, "~/") && !strings.HasPrefix(rule, "/") && !filepath.IsAbs(rule) {
+		return "", fmt.Errorf("git matches '%s' at any depth, which a path rule cannot express", pattern)
+	}
+	rule = strings.TrimSuffix(rule, "/**")
+	rule = strings.TrimSuffix(rule, "/")
+	rule = strings.TrimSuffix(rule, "/.git")
+	if strings.Contains(rule, "**") {
+		return "", fmt.Errorf("git matches '%s' at any depth, which a path rule cannot express", pattern)
+	}
+	if ignoreCase {
+		if strings.ContainsAny(rule, "[\\") {
+			return "", fmt.Errorf("'%s' cannot be matched case-insensitively by a path rule", pattern)
+		}
+		home, rest := "", rule
+		if strings.HasPrefix(rule, "~/") {
+			home, rest = "~/", rule[2:]
+		}
+		var folded strings.Builder
+		for _, r := range rest {
+			if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper {
+				fmt.Fprintf(&folded, "[%c%c]", lower, upper)
+			} else {
+				folded.WriteRune(r)
+			}
+		}
+		rule = home + folded.String()
+	}
+	return rule, nil
+}
+
+// hasconfigRemote converts the URL of a hasconfig:remote.*.url: condition into
+// the remote of a repository assignment. Assignments name one repository, so
+// URL patterns are rejected.
+func hasconfigRemote(url string) (string, error) {
+	if strings.ContainsAny(url, "*?[") {
+		return "", fmt.Errorf("'%s' matches several repositories; use a path rule instead", url)
+	}
+	return config.NormalizeRemote(url)
+}
+
+// includeNameHint picks a name for an included identity: the suffix of files
+// like ~/.gitconfig-work, otherwise the last directory of the gitdir pattern.
+func includeNameHint(includePath, ruleDir string) string {
+	base := strings.TrimPrefix(filepath.Base(includePath), ".")
+	for _, prefix := range []string{"gitconfig-", "gitconfig_", "gitconfig."} {
+		if strings.HasPrefix(base, prefix) && len(base) > len(prefix) {
+			return strings.TrimPrefix(base, prefix)
+		}
+	}
+	return filepath.Base(ruleDir)
+}
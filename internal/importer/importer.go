@@ -0,0 +1,88 @@
+// Package importer proposes gham contexts, path rules and tokens from an
+// existing multi-account setup: git's includeIf identities, stored git
+// credentials, and the gh CLI's accounts. Proposals are returned as bundles,
+// so 'gham import' can merge them like an exported bundle.
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/bundle"
+)
+
+// Import sources.
+const (
+	SourceGitConfig      = "gitconfig"
+	SourceGitCredentials = "git-credentials"
+	SourceGH             = "gh"
+)
+
+// Sources lists the names accepted by Discover.
+var Sources = []string{SourceGitConfig, SourceGitCredentials, SourceGH}
+
+// Discover reads the given source from its default location, or from path if set.
+func Discover(source, path string) (*bundle.Bundle, error) {
+	switch source {
+	case SourceGitConfig:
+		if path == "" {
+			path = filepath.Join(homeDir(), ".gitconfig")
+		}
+		return FromGitConfig(path)
+	case SourceGitCredentials:
+		if path == "" {
+			path = filepath.Join(homeDir(), ".git-credentials")
+		}
+		return FromGitCredentials(path)
+	case SourceGH:
+		if path == "" {
+			path = ghHostsPath()
+		}
+		return FromGH(path)
+	default:
+		return nil, fmt.Errorf("unknown import source '%s' (use %s)", source, strings.Join(Sources, ", "))
+	}
+}
+
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return home
+}
+
+// expandPath expands "~/" and makes a path relative to base absolute, as git does for include paths.
+func expandPath(path, base string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir(), strings.TrimPrefix(path, "~"))
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(base, path)
+	}
+	return path
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// contextName turns a hint such as a directory, file or user name into a context name.
+func contextName(hint string) string {
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(hint), "-"), "-.")
+	if name == "" {
+		return "imported"
+	}
+	return name
+}
+
+// uniqueName appends a number to name until it is not taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,117 @@
+package keyring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/99designs/keyring"
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/utils"
+)
+
+const (
+	// EnvPassphrase holds the passphrase for the encrypted file backend.
+	EnvPassphrase = "GHAM_KEYRING_PASSPHRASE"
+	// EnvPassphraseCommand names a command whose stdout is the file backend passphrase.
+	EnvPassphraseCommand = "GHAM_KEYRING_PASSPHRASE_CMD"
+	// EnvFileDir overrides the directory used by the encrypted file backend.
+	EnvFileDir = "GHAM_KEYRING_FILE_DIR"
+
+	fileKeyringDirName = "keyring"
+)
+
+// fileKeyringDir returns the directory holding the encrypted token files for
+// one namespace (e.g. "keyring", "keyring-v2", "profiles/work/keyring").
+// GHAM_KEYRING_FILE_DIR replaces the whole path up to the "keyring" part,
+// keeping any version suffix, so it applies to whichever profile is selected.
+func fileKeyringDir(name string) (string, error) {
+	if dir := strings.TrimSpace(os.Getenv(EnvFileDir)); dir != "" {
+		return filepath.Clean(dir) + strings.TrimPrefix(filepath.Base(name), fileKeyringDirName), nil
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, name), nil
+}
+
+// checkFilePermissions refuses to use a keyring directory (or any item in it)
+// that is readable or writable by users other than the owner.
+func checkFilePermissions(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Unix permission bits are not meaningful on Windows; rely on ACLs.
+		return nil
+	}
+	fi, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0700)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat file keyring directory '%s': %w", dir, err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("file keyring path '%s' is not a directory", dir)
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("file keyring directory '%s' has permissions %04o; refusing to use it (run 'chmod 700 %s')", dir, fi.Mode().Perm(), dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read file keyring directory '%s': %w", dir, err)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat file keyring item '%s': %w", entry.Name(), err)
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			itemPath := filepath.Join(dir, entry.Name())
+			return fmt.Errorf("file keyring item '%s' has permissions %04o; refusing to use it (run 'chmod 600 %s')", itemPath, info.Mode().Perm(), itemPath)
+		}
+	}
+	return nil
+}
+
+// filePassphrase returns a keyring.PromptFunc that resolves the file backend
+// passphrase from, in order: GHAM_KEYRING_PASSPHRASE, GHAM_KEYRING_PASSPHRASE_CMD,
+// or an interactive prompt. The passphrase is confirmed when the keyring is empty,
+// since a typo would otherwise lock every token stored afterwards.
+func filePassphrase(dir string) keyring.PromptFunc {
+	return func(prompt string) (string, error) {
+		if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+			return passphrase, nil
+		}
+		if command := strings.TrimSpace(os.Getenv(EnvPassphraseCommand)); command != "" {
+			passphrase, err := utils.RunShellCommand(command)
+			if err != nil {
+				return "", fmt.Errorf("passphrase command failed: %w", err)
+			}
+			if passphrase == "" {
+				return "", fmt.Errorf("passphrase command returned an empty passphrase")
+			}
+			return passphrase, nil
+		}
+
+		passphrase, err := utils.PromptForInput(prompt+": ", true)
+		if err != nil {
+			return "", err
+		}
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase cannot be empty")
+		}
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+			confirm, err := utils.PromptForInput("Confirm passphrase: ", true)
+			if err != nil {
+				return "", err
+			}
+			if confirm != passphrase {
+				return "", fmt.Errorf("passphrases do not match")
+			}
+		}
+		return passphrase, nil
+	}
+}
@@ -0,0 +1,159 @@
+package keyring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func TestCheckFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not checked on Windows")
+	}
+	tests := []struct {
+		name     string
+		dirMode  os.FileMode // 0 to leave the directory to checkFilePermissions
+		itemMode os.FileMode // 0 for no item
+		wantErr  string
+	}{
+		{name: "created when missing"},
+		{name: "private", dirMode: 0700, itemMode: 0600},
+		{name: "readable directory", dirMode: 0755, wantErr: "chmod 700"},
+		{name: "readable item", dirMode: 0700, itemMode: 0644, wantErr: "chmod 600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "keyring")
+			if tt.dirMode != 0 {
+				if err := os.Mkdir(dir, tt.dirMode); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(dir, tt.dirMode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.itemMode != 0 {
+				if err := os.WriteFile(filepath.Join(dir, "work"), nil, tt.itemMode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := checkFilePermissions(dir)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("checkFilePermissions() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("checkFilePermissions() = %v, want it to suggest %q", err, tt.wantErr)
+			}
+			if fi, err := os.Stat(dir); tt.dirMode == 0 && (err != nil || fi.Mode().Perm() != 0700) {
+				t.Errorf("directory created as %v (%v), want 0700", fi.Mode().Perm(), err)
+			}
+		})
+	}
+
+	notDir := filepath.Join(t.TempDir(), "keyring")
+	if err := os.WriteFile(notDir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFilePermissions(notDir); err == nil {
+		t.Error("checkFilePermissions() accepted a file")
+	}
+}
+
+func TestFileKeyringDir(t *testing.T) {
+	t.Setenv(EnvFileDir, "/srv/gham/tokens/")
+	for name, want := range map[string]string{
+		"keyring":                "/srv/gham/tokens",
+		"keyring-v2":             "/srv/gham/tokens-v2",
+		"profiles/work/keyring":  "/srv/gham/tokens",
+		"profiles/work/keyring2": "/srv/gham/tokens2",
+	} {
+		if got, err := fileKeyringDir(name); err != nil || got != filepath.FromSlash(want) {
+			t.Errorf("fileKeyringDir(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestFilePassphrase(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the passphrase commands use sh")
+	}
+	tests := []struct {
+		name    string
+		env     string
+		command string
+		want    string
+		wantErr bool
+	}{
+		{name: "variable", env: "pw", command: "echo other", want: "pw"},
+		{name: "command", command: "printf 'from-cmd\\n'", want: "from-cmd"},
+		{name: "command fails", command: "exit 3", wantErr: true},
+		{name: "empty command output", command: "true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvPassphrase, tt.env)
+			t.Setenv(EnvPassphraseCommand, tt.command)
+			got, err := filePassphrase(t.TempDir())("Passphrase")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("passphrase = %q, %v, want %q (error: %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keyring")
+	t.Setenv(EnvFileDir, dir)
+	t.Setenv(EnvPassphraseCommand, "")
+	t.Setenv(EnvPassphrase, "correct horse")
+	saved := config.Current()
+	config.SetCurrent(config.NewStore("", ""))
+	t.Cleanup(func() { config.SetCurrent(saved) })
+
+	s, err := OpenStore("file", CurrentVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetToken("work", "ghp_work"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("keyring directory holds %v (%v), want one item", entries, err)
+	}
+	item := filepath.Join(dir, entries[0].Name())
+	data, err := os.ReadFile(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ghp_work") {
+		t.Error("the token is stored in plain text")
+	}
+	if fi, _ := os.Stat(item); runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		t.Errorf("item permissions = %v, want owner only", fi.Mode().Perm())
+	}
+
+	// Reopened, as a later command would.
+	s, err = OpenStore("file", CurrentVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err := s.Token("work"); err != nil || token != "ghp_work" {
+		t.Errorf("Token() = %q, %v, want the stored token", token, err)
+	}
+	if _, err := s.Token("personal"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Token() of a missing context = %v, want ErrNotFound", err)
+	}
+
+	t.Setenv(EnvPassphrase, "wrong")
+	if s, err = OpenStore("file", CurrentVersion); err == nil {
+		if _, err = s.Token("work"); err == nil {
+			t.Error("Token() succeeded with a wrong passphrase")
+		}
+	}
+}
@@ -4,87 +4,320 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/99designs/keyring"
 	"github.com/riad804/github-auth-manager/internal/config"
 )
 
-var kr keyring.Keyring
-var keyringErr error // Store init error
+const (
+	// EnvBackend pins the keyring backend (e.g. "secret-service", "file", "memory").
+	EnvBackend = "GHAM_KEYRING_BACKEND"
+	// EnvTimeout bounds each keyring backend call (Go duration, e.g. "10s").
+	EnvTimeout = "GHAM_KEYRING_TIMEOUT"
 
-func init() {
-	// Define allowed backends for more predictable behavior
-	// Order matters: first successful one is used.
-	allowedBackends := []keyring.BackendType{
-		keyring.KeychainBackend,      // macOS
-		keyring.SecretServiceBackend, // Linux
-		keyring.WinCredBackend,       // Windows
-		keyring.KWalletBackend,       // Linux (KDE)
-		keyring.PassBackend,          // Linux/macOS (pass utility)
-		// keyring.FileBackend,          // Fallback (less secure, consider if needed)
-	}
-
-	kr, keyringErr = keyring.Open(keyring.Config{
-		ServiceName:              config.KeyringService,
-		AllowedBackends:          allowedBackends,
+	// BackendAuto tries the system keyring services in order.
+	BackendAuto = "auto"
+	// BackendMemory keeps tokens in process memory only. Intended for tests.
+	BackendMemory = "memory"
+
+	DefaultTimeout = 10 * time.Second
+)
+
+// autoBackends is the detection order used when no backend is pinned.
+// Order matters: first successful one is used.
+var autoBackends = []keyring.BackendType{
+	keyring.KeychainBackend,      // macOS
+	keyring.SecretServiceBackend, // Linux
+	keyring.WinCredBackend,       // Windows
+	keyring.KWalletBackend,       // Linux (KDE)
+	keyring.PassBackend,          // Linux/macOS (pass utility)
+	// The encrypted file backend is opt-in only (pin it with "file"): it is meant
+	// for headless servers, WSL and containers where no system keyring service exists.
+}
+
+// ErrNotFound is returned (wrapped) when a context has no token in the keyring.
+var ErrNotFound = errors.New("no token found")
+
+var (
+	openOnce        sync.Once
+	defaultStore    *Store
+	keyringErr      error // Store open error
+	backendOverride string
+)
+
+// SetBackend pins the backend for this process, taking precedence over
+// GHAM_KEYRING_BACKEND and the config file. It must be called before the
+// keyring is first used; later calls have no effect.
+func SetBackend(name string) error {
+	name = strings.TrimSpace(name)
+	if err := ValidateBackend(name); err != nil {
+		return err
+	}
+	backendOverride = name
+	return nil
+}
+
+// Backends lists the backend names accepted by SetBackend, GHAM_KEYRING_BACKEND
+// and the keyring.backend config key.
+func Backends() []string {
+	names := []string{BackendAuto}
+	for _, b := range keyring.AvailableBackends() {
+		names = append(names, string(b))
+	}
+	return append(names, BackendMemory)
+}
+
+// ValidateBackend returns an error if name is not a known backend. Empty means auto.
+func ValidateBackend(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, b := range Backends() {
+		if b == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown keyring backend '%s' (available: %s)", name, strings.Join(Backends(), ", "))
+}
+
+// SelectedBackend resolves the backend name: --keyring-backend flag, then
+// GHAM_KEYRING_BACKEND, then keyring.backend in config.yaml, then auto.
+func SelectedBackend() string {
+	if backendOverride != "" {
+		return backendOverride
+	}
+	if env := strings.TrimSpace(os.Getenv(EnvBackend)); env != "" {
+		return env
+	}
+	if cfgBackend := strings.TrimSpace(config.Current().Config.Keyring.Backend); cfgBackend != "" {
+		return cfgBackend
+	}
+	return BackendAuto
+}
+
+// callTimeout resolves the per-call timeout from GHAM_KEYRING_TIMEOUT or config.
+func callTimeout() (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv(EnvTimeout))
+	if raw == "" {
+		raw = strings.TrimSpace(config.Current().Config.Keyring.Timeout)
+	}
+	if raw == "" {
+		return DefaultTimeout, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid keyring timeout '%s': expected a positive duration such as '10s'", raw)
+	}
+	return d, nil
+}
+
+// openBackend opens the named backend for the given item format version. It
+// also returns the backend actually opened, which auto-detection picks, and
+// whether its items are scoped to gham (see namespace.scoped).
+func openBackend(name string, version int) (keyring.Keyring, bool, error) {
+	if err := ValidateBackend(name); err != nil {
+		return nil, false, err
+	}
+	if name == BackendMemory {
+		return keyring.NewArrayKeyring(nil), true, nil
+	}
+
+	ns, err := namespaceFor(version, config.Current().Profile, config.Current().ConfigID())
+	if err != nil {
+		return nil, false, err
+	}
+	cfg := keyring.Config{
+		ServiceName:              ns.service,
 		KeychainTrustApplication: true, // macOS: trust the application path
 		// For Linux Secret Service:
-		LibSecretCollectionName: "gham",
+		LibSecretCollectionName: ns.collection,
 		// For pass:
-		PassCmd: "pass",
+		PassCmd:    "pass",
+		PassPrefix: ns.prefix,
 		// PassDir: "~/.password-store", // if non-standard
+		WinCredPrefix: ns.prefix,
+		KWalletFolder: ns.kwalletFolder,
+	}
+
+	candidates := []keyring.BackendType{keyring.BackendType(name)}
+	switch name {
+	case "", BackendAuto:
+		candidates = autoBackends
+	case string(keyring.FileBackend):
+		dir, err := fileKeyringDir(ns.fileDir)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := checkFilePermissions(dir); err != nil {
+			return nil, false, err
+		}
+		cfg.AllowedBackends = []keyring.BackendType{keyring.FileBackend}
+		cfg.FileDir = dir
+		cfg.FilePasswordFunc = filePassphrase(dir)
+		// The file backend may prompt for a passphrase, so it is not subject to the call timeout.
+		opened, err := keyring.Open(cfg)
+		return opened, true, err
+	}
+
+	timeout, err := callTimeout()
+	if err != nil {
+		return nil, false, err
+	}
+	// Candidates are opened one at a time, in order, so we know which one
+	// auto-detection settled on.
+	err = keyring.ErrNoAvailImpl
+	for _, backend := range candidates {
+		cfg.AllowedBackends = []keyring.BackendType{backend}
+		var opened keyring.Keyring
+		openErr := withTimeout(timeout, "open", func() error {
+			var err error
+			opened, err = keyring.Open(cfg)
+			return err
+		})
+		if openErr == nil {
+			return &timeoutKeyring{inner: opened, timeout: timeout}, ns.scoped(string(backend)), nil
+		}
+		if !errors.Is(openErr, keyring.ErrNoAvailImpl) || err == keyring.ErrNoAvailImpl {
+			err = openErr
+		}
+	}
+	return nil, false, err
+}
+
+// Store is a keyring backend opened for one item format version.
+type Store struct {
+	Backend string
+	Version int
+	kr      keyring.Keyring
+	scoped  bool // Keys lists gham's items only
+}
+
+// OpenStore opens the named backend ("" or "auto" to auto-detect) for the
+// given item format version. Most callers want the package-level functions,
+// which use the backend selected by flag, environment or config.
+func OpenStore(backend string, version int) (*Store, error) {
+	opened, scoped, err := openBackend(backend, version)
+	if err != nil {
+		return nil, err
+	}
+	if backend == "" {
+		backend = BackendAuto
+	}
+	return &Store{Backend: backend, Version: version, kr: opened, scoped: scoped}, nil
+}
+
+// Token returns the token stored for contextName, or an error wrapping ErrNotFound.
+func (s *Store) Token(contextName string) (string, error) {
+	// Key for keyring item should be unique per token. Context name is good.
+	item, err := s.kr.Get(contextName)
+	if err != nil {
+		if errors.Is(err, keyring.ErrKeyNotFound) || errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w for context '%s'", ErrNotFound, contextName)
+		}
+		return "", fmt.Errorf("failed to get token for context '%s' from keyring: %w", contextName, err)
+	}
+	token, err := decodeItem(s.Version, item.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode token for context '%s': %w", contextName, err)
+	}
+	return token, nil
+}
+
+// SetToken stores token for contextName, replacing any existing item.
+func (s *Store) SetToken(contextName, token string) error {
+	data, err := encodeItem(s.Version, token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token for context '%s': %w", contextName, err)
+	}
+	err = s.kr.Set(keyring.Item{
+		Key:         contextName,
+		Data:        data,
+		Label:       fmt.Sprintf("GHAM PAT for context '%s'", contextName),
+		Description: "GitHub Personal Access Token managed by GHAM CLI.",
 	})
+	if err != nil {
+		return fmt.Errorf("failed to store token for context '%s' in keyring: %w", contextName, err)
+	}
+	return nil
+}
 
-	if keyringErr != nil {
-		// This error will be checked by functions using the keyring
-		fmt.Fprintf(os.Stderr, "Warning: GHAM could not initialize system keyring: %v\n", keyringErr)
-		fmt.Fprintln(os.Stderr, "PATs will not be stored securely. Commands requiring tokens may fail or be insecure.")
-		fmt.Fprintln(os.Stderr, "Ensure you have a compatible keyring service (libsecret, GNOME Keyring, KWallet, macOS Keychain, Windows Credential Manager).")
-		// For MVP, we allow the app to run, but operations needing tokens will fail more gracefully.
+// DeleteToken removes the item for contextName. A missing item is not an error.
+func (s *Store) DeleteToken(contextName string) error {
+	err := s.kr.Remove(contextName)
+	// Do not error if key is not found, as it might have been deleted manually or never existed.
+	// The file backend reports a missing item as a plain "file does not exist" error.
+	if err != nil && !errors.Is(err, keyring.ErrKeyNotFound) && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete token for context '%s' from keyring: %w", contextName, err)
+	}
+	return nil
+}
+
+// Scoped reports whether Keys lists gham's items only. On a backend shared with
+// other applications (pass without a prefix, KWallet's default folder) it
+// lists theirs too, so keys without a context cannot be treated as gham's.
+func (s *Store) Scoped() bool {
+	return s.scoped
+}
+
+// Keys lists the item keys (context names) stored in this backend.
+func (s *Store) Keys() ([]string, error) {
+	keys, err := s.kr.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keyring items: %w", err)
 	}
+	return keys, nil
+}
+
+// open lazily opens the selected backend on first use, so commands that never
+// touch a token (version, git status without a context, ...) do not pay for it.
+func open() {
+	openOnce.Do(func() {
+		backend := SelectedBackend()
+		defaultStore, keyringErr = OpenStore(backend, CurrentVersion)
+		if keyringErr != nil && (backend == "" || backend == BackendAuto) {
+			keyringErr = fmt.Errorf("%w. Ensure you have a compatible keyring service (libsecret, GNOME Keyring, KWallet, macOS Keychain, Windows Credential Manager), or set %s=file to use the encrypted file backend", keyringErr, EnvBackend)
+		}
+	})
 }
 
 func checkKeyring() error {
+	open()
 	if keyringErr != nil {
 		return fmt.Errorf("keyring is not available: %w", keyringErr)
 	}
-	if kr == nil { // Should be covered by keyringErr, but as a safeguard
+	if defaultStore == nil { // Should be covered by keyringErr, but as a safeguard
 		return errors.New("keyring not initialized (keyring instance is nil)")
 	}
 	return nil
 }
 
+// Default returns the store for the selected backend, opening it if needed.
+func Default() (*Store, error) {
+	if err := checkKeyring(); err != nil {
+		return nil, err
+	}
+	return defaultStore, nil
+}
+
 func StoreToken(contextName, token string) error {
 	if err := checkKeyring(); err != nil {
 		return err
 	}
-	// Key for keyring item should be unique per token. Context name is good.
-	itemKey := contextName
-	err := kr.Set(keyring.Item{
-		Key:         itemKey,
-		Data:        []byte(token),
-		Label:       fmt.Sprintf("GHAM PAT for context '%s'", contextName),
-		Description: "GitHub Personal Access Token managed by GHAM CLI.",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to store token for context '%s' in keyring: %w", contextName, err)
-	}
-	return nil
+	return defaultStore.SetToken(contextName, token)
 }
 
 func GetToken(contextName string) (string, error) {
 	if err := checkKeyring(); err != nil {
 		return "", err
 	}
-	itemKey := contextName
-	item, err := kr.Get(itemKey)
-	if err != nil {
-		if errors.Is(err, keyring.ErrKeyNotFound) {
-			return "", fmt.Errorf("no token found for context '%s' in keyring. Please ensure it was added correctly", contextName)
-		}
-		return "", fmt.Errorf("failed to get token for context '%s' from keyring: %w", contextName, err)
+	token, err := defaultStore.Token(contextName)
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("%w for context '%s' in keyring. Please ensure it was added correctly", ErrNotFound, contextName)
 	}
-	return string(item.Data), nil
+	return token, err
 }
 
 func DeleteToken(contextName string) error {
@@ -93,11 +326,15 @@ func DeleteToken(contextName string) error {
 		// Return the error so caller can decide how to handle.
 		return err
 	}
-	itemKey := contextName
-	err := kr.Remove(itemKey)
-	// Do not error if key is not found, as it might have been deleted manually or never existed.
-	if err != nil && !errors.Is(err, keyring.ErrKeyNotFound) {
-		return fmt.Errorf("failed to delete token for context '%s' from keyring: %w", contextName, err)
-	}
-	return nil
+	return defaultStore.DeleteToken(contextName)
+}
+
+func init() {
+	// Opening the keyring stays lazy; this only lets 'gham config validate' check the backend name.
+	config.RegisterValidator(func(cfg *config.AppConfig) []config.Problem {
+		if err := ValidateBackend(cfg.Keyring.Backend); err != nil {
+			return []config.Problem{{Severity: config.SeverityError, Message: "keyring.backend: " + err.Error()}}
+		}
+		return nil
+	})
 }
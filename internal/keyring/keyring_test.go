@@ -0,0 +1,130 @@
+package keyring
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// hungKeyring is a backend whose calls never return, like a D-Bus Secret
+// Service that does not answer.
+type hungKeyring struct{ keyring.Keyring }
+
+func (hungKeyring) Get(string) (keyring.Item, error) { select {} }
+func (hungKeyring) Set(keyring.Item) error           { select {} }
+func (hungKeyring) Keys() ([]string, error)          { select {} }
+
+func TestTimeoutKeyring(t *testing.T) {
+	fast := keyring.NewArrayKeyring([]keyring.Item{{Key: "work", Data: []byte("ghp_x")}})
+	tests := []struct {
+		name    string
+		inner   keyring.Keyring
+		wantErr bool
+	}{
+		{"answering backend", fast, false},
+		{"hung backend", hungKeyring{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr := &timeoutKeyring{inner: tt.inner, timeout: 20 * time.Millisecond}
+			start := time.Now()
+			_, getErr := kr.Get("work")
+			setErr := kr.Set(keyring.Item{Key: "personal"})
+			_, keysErr := kr.Keys()
+			for op, err := range map[string]error{"Get": getErr, "Set": setErr, "Keys": keysErr} {
+				if (err != nil) != tt.wantErr {
+					t.Errorf("%s() error = %v, want error: %v", op, err, tt.wantErr)
+				}
+				if err != nil && !strings.Contains(err.Error(), EnvTimeout) {
+					t.Errorf("%s() error = %v, want it to mention %s", op, err, EnvTimeout)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("calls took %s, want them bounded by the timeout", elapsed)
+			}
+		})
+	}
+}
+
+// resetDefault forgets the process-wide backend selection and store.
+func resetDefault(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		openOnce, defaultStore, keyringErr, backendOverride = sync.Once{}, nil, nil, ""
+	}
+	reset()
+	savedConfig := config.Current()
+	t.Cleanup(func() {
+		reset()
+		config.SetCurrent(savedConfig)
+	})
+}
+
+func TestSelectedBackend(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   string
+		env    string
+		config string
+		want   string
+	}{
+		{name: "nothing set", want: BackendAuto},
+		{name: "config", config: "file", want: "file"},
+		{name: "env over config", env: BackendMemory, config: "file", want: BackendMemory},
+		{name: "flag over env", flag: "file", env: BackendMemory, config: BackendAuto, want: "file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetDefault(t)
+			t.Setenv(EnvBackend, tt.env)
+			store := config.NewStore("", "")
+			store.Config.Keyring.Backend = tt.config
+			config.SetCurrent(store)
+			if err := SetBackend(tt.flag); err != nil {
+				t.Fatal(err)
+			}
+			if got := SelectedBackend(); got != tt.want {
+				t.Errorf("SelectedBackend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if err := SetBackend("no-such-backend"); err == nil {
+		t.Error("SetBackend() accepted an unknown backend")
+	}
+}
+
+func TestKeyringOpensOnFirstUse(t *testing.T) {
+	resetDefault(t)
+	// A backend that cannot be opened must not fail commands that never use it.
+	t.Setenv(EnvBackend, BackendAuto)
+	t.Setenv(EnvTimeout, "not-a-duration")
+	config.SetCurrent(config.NewStore("", ""))
+	if SelectedBackend() != BackendAuto || defaultStore != nil || keyringErr != nil {
+		t.Fatal("selecting a backend opened it")
+	}
+	if _, err := GetToken("work"); err == nil || !strings.Contains(err.Error(), "not-a-duration") {
+		t.Errorf("GetToken() error = %v, want the open error on first use", err)
+	}
+
+	resetDefault(t)
+	if err := SetBackend(BackendMemory); err != nil {
+		t.Fatal(err)
+	}
+	if defaultStore != nil {
+		t.Fatal("SetBackend() opened the keyring")
+	}
+	if err := StoreToken("work", "ghp_x"); err != nil {
+		t.Fatal(err)
+	}
+	if defaultStore == nil || defaultStore.Backend != BackendMemory {
+		t.Fatalf("store after first use = %+v, want the memory backend", defaultStore)
+	}
+	if _, err := GetToken("personal"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetToken() of a missing token = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,84 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// Migration outcomes of a single context.
+const (
+	MigrateCopied  = "copied"
+	MigrateSkipped = "skipped"
+	MigrateFailed  = "failed"
+)
+
+// MigrateOptions controls what Migrate may change besides writing new items.
+type MigrateOptions struct {
+	DeleteSource bool // delete each source item once its copy is verified
+	Overwrite    bool // replace a different token already in the destination
+}
+
+// MigrateResult is the outcome of copying one context's token.
+type MigrateResult struct {
+	Context string
+	Outcome string // MigrateCopied, MigrateSkipped or MigrateFailed
+	Detail  string // why it was skipped or failed, or what happened to the source item
+}
+
+// Migrate copies the token of every keyring-backed context from src to dst and
+// reads each copy back to verify it. Source items are deleted only with
+// opts.DeleteSource, and only after their copy has been verified.
+func Migrate(src, dst *Store, contexts []config.Context, opts MigrateOptions) []MigrateResult {
+	results := make([]MigrateResult, 0, len(contexts))
+	for _, ctx := range contexts {
+		results = append(results, migrateOne(src, dst, ctx, opts))
+	}
+	return results
+}
+
+func migrateOne(src, dst *Store, ctx config.Context, opts MigrateOptions) MigrateResult {
+	skipped := func(detail string) MigrateResult {
+		return MigrateResult{Context: ctx.Name, Outcome: MigrateSkipped, Detail: detail}
+	}
+	failed := func(detail string, err error) MigrateResult {
+		return MigrateResult{Context: ctx.Name, Outcome: MigrateFailed, Detail: fmt.Sprintf("%s: %v", detail, err)}
+	}
+
+	if !ctx.UsesKeyring() {
+		return skipped(fmt.Sprintf("token comes from %s, not the keyring", ctx.TokenSourceType()))
+	}
+	token, err := src.Token(ctx.Name)
+	if errors.Is(err, ErrNotFound) {
+		return skipped("no token in source")
+	}
+	if err != nil {
+		return failed("to read", err)
+	}
+
+	existing, err := dst.Token(ctx.Name)
+	if err == nil && existing != token && !opts.Overwrite {
+		return skipped("destination already holds a different token; use --overwrite")
+	}
+
+	if err := dst.SetToken(ctx.Name, token); err != nil {
+		return failed("to write", err)
+	}
+	if readBack, err := dst.Token(ctx.Name); err != nil || readBack != token {
+		if err == nil {
+			err = errors.New("token read back does not match")
+		}
+		return failed("verification", err)
+	}
+
+	result := MigrateResult{Context: ctx.Name, Outcome: MigrateCopied, Detail: "copied and verified"}
+	if opts.DeleteSource {
+		if err := src.DeleteToken(ctx.Name); err != nil {
+			result.Detail += fmt.Sprintf("; warning: could not delete source item: %v", err)
+		} else {
+			result.Detail += "; source item deleted"
+		}
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package keyring
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func TestMigrate(t *testing.T) {
+	envSource := &config.TokenSource{Type: config.TokenSourceEnv, Env: "GH_TOKEN"}
+	contexts := []config.Context{{Name: "work"}, {Name: "personal"}, {Name: "ci", TokenSource: envSource}}
+
+	tests := []struct {
+		name         string
+		src, dst     map[string]string // tokens by context
+		opts         MigrateOptions
+		wantOutcomes []string // per context, in order
+		wantSrc      map[string]string
+		wantDst      map[string]string
+	}{
+		{
+			name:         "copy keeps the source",
+			src:          map[string]string{"work": "w", "personal": "p"},
+			wantOutcomes: []string{MigrateCopied, MigrateCopied, MigrateSkipped},
+			wantSrc:      map[string]string{"work": "w", "personal": "p"},
+			wantDst:      map[string]string{"work": "w", "personal": "p"},
+		},
+		{
+			name:         "missing source token is skipped",
+			src:          map[string]string{"work": "w"},
+			wantOutcomes: []string{MigrateCopied, MigrateSkipped, MigrateSkipped},
+			wantSrc:      map[string]string{"work": "w"},
+			wantDst:      map[string]string{"work": "w"},
+		},
+		{
+			name:         "different destination token is kept",
+			src:          map[string]string{"work": "new"},
+			dst:          map[string]string{"work": "old"},
+			wantOutcomes: []string{MigrateSkipped, MigrateSkipped, MigrateSkipped},
+			wantSrc:      map[string]string{"work": "new"},
+			wantDst:      map[string]string{"work": "old"},
+		},
+		{
+			name:         "overwrite replaces it",
+			src:          map[string]string{"work": "new"},
+			dst:          map[string]string{"work": "old"},
+			opts:         MigrateOptions{Overwrite: true},
+			wantOutcomes: []string{MigrateCopied, MigrateSkipped, MigrateSkipped},
+			wantSrc:      map[string]string{"work": "new"},
+			wantDst:      map[string]string{"work": "new"},
+		},
+		{
+			name:         "delete source after verifying",
+			src:          map[string]string{"work": "w", "personal": "p"},
+			opts:         MigrateOptions{DeleteSource: true},
+			wantOutcomes: []string{MigrateCopied, MigrateCopied, MigrateSkipped},
+			wantSrc:      map[string]string{},
+			wantDst:      map[string]string{"work": "w", "personal": "p"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, dst := newMemoryStore(t, true), newMemoryStore(t, true)
+			setTokens(t, src, tt.src)
+			setTokens(t, dst, tt.dst)
+
+			var outcomes []string
+			for _, r := range Migrate(src, dst, contexts, tt.opts) {
+				outcomes = append(outcomes, r.Outcome)
+			}
+			if !reflect.DeepEqual(outcomes, tt.wantOutcomes) {
+				t.Errorf("outcomes = %v, want %v", outcomes, tt.wantOutcomes)
+			}
+			if got := tokens(t, src); !reflect.DeepEqual(got, tt.wantSrc) {
+				t.Errorf("source = %v, want %v", got, tt.wantSrc)
+			}
+			if got := tokens(t, dst); !reflect.DeepEqual(got, tt.wantDst) {
+				t.Errorf("destination = %v, want %v", got, tt.wantDst)
+			}
+		})
+	}
+}
+
+func setTokens(t *testing.T, s *Store, tokens map[string]string) {
+	t.Helper()
+	for name, token := range tokens {
+		if err := s.SetToken(name, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tokens returns every item of s by key.
+func tokens(t *testing.T, s *Store) map[string]string {
+	t.Helper()
+	keys, err := s.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, key := range keys {
+		if got[key], err = s.Token(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return got
+}
@@ -0,0 +1,61 @@
+package keyring
+
+import (
+	"sort"
+)
+
+// Reconciliation compares the items in a keyring store with the configured contexts.
+type Reconciliation struct {
+	// Orphans are keyring items with no matching context, typically left behind
+	// when 'context add' rolled back or 'context remove' could not delete the
+	// token.
+	Orphans []string
+	// Missing are contexts that have no token in the keyring.
+	Missing []string
+	// Unscoped is set when the backend also lists the items of other
+	// applications (see Store.Scoped). Orphans are then not reported, since
+	// they cannot be told apart from those items.
+	Unscoped bool
+}
+
+// Clean reports whether the keyring and the configured contexts agree.
+func (r Reconciliation) Clean() bool {
+	return len(r.Orphans) == 0 && len(r.Missing) == 0
+}
+
+// Reconcile lists orphaned items and contexts without a token. It only reads
+// item keys, so it never needs to decrypt or unlock individual tokens. On an
+// unscoped backend only contexts without a token are listed.
+func (s *Store) Reconcile(contextNames []string) (Reconciliation, error) {
+	keys, err := s.Keys()
+	if err != nil {
+		return Reconciliation{}, err
+	}
+
+	stored := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		stored[key] = true
+	}
+	configured := make(map[string]bool, len(contextNames))
+	for _, name := range contextNames {
+		configured[name] = true
+	}
+
+	r := Reconciliation{Unscoped: !s.scoped}
+	for _, key := range keys {
+		if r.Unscoped {
+			break
+		}
+		if !configured[key] {
+			r.Orphans = append(r.Orphans, key)
+		}
+	}
+	for _, name := range contextNames {
+		if !stored[name] {
+			r.Missing = append(r.Missing, name)
+		}
+	}
+	sort.Strings(r.Orphans)
+	sort.Strings(r.Missing)
+	return r, nil
+}
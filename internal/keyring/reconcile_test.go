@@ -0,0 +1,104 @@
+package keyring
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/99designs/keyring"
+)
+
+// newMemoryStore returns a memory-backed store holding items, scoped or not.
+func newMemoryStore(t *testing.T, scoped bool, items ...string) *Store {
+	t.Helper()
+	s := &Store{Backend: BackendMemory, Version: CurrentVersion, kr: keyring.NewArrayKeyring(nil), scoped: scoped}
+	for _, key := range items {
+		if err := s.kr.Set(keyring.Item{Key: key, Data: []byte("secret")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name         string
+		scoped       bool
+		items        []string
+		contexts     []string
+		wantOrphans  []string
+		wantMissing  []string
+		wantUnscoped bool
+	}{
+		{
+			name:     "in sync",
+			scoped:   true,
+			items:    []string{"work", "personal"},
+			contexts: []string{"personal", "work"},
+		},
+		{
+			name:        "orphan and missing",
+			scoped:      true,
+			items:       []string{"work", "old"},
+			contexts:    []string{"work", "personal"},
+			wantOrphans: []string{"old"},
+			wantMissing: []string{"personal"},
+		},
+		{
+			// e.g. pass without a prefix: the whole password store is listed.
+			name:         "foreign item in an unscoped backend",
+			scoped:       false,
+			items:        []string{"work", "email/gmail", "bank"},
+			contexts:     []string{"work", "personal"},
+			wantMissing:  []string{"personal"},
+			wantUnscoped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryStore(t, tt.scoped, tt.items...)
+			r, err := s.Reconcile(tt.contexts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(r.Orphans, tt.wantOrphans) {
+				t.Errorf("Orphans = %v, want %v", r.Orphans, tt.wantOrphans)
+			}
+			if !reflect.DeepEqual(r.Missing, tt.wantMissing) {
+				t.Errorf("Missing = %v, want %v", r.Missing, tt.wantMissing)
+			}
+			if r.Unscoped != tt.wantUnscoped {
+				t.Errorf("Unscoped = %v, want %v", r.Unscoped, tt.wantUnscoped)
+			}
+			// Reconciling never touches items.
+			keys, _ := s.Keys()
+			if len(keys) != len(tt.items) {
+				t.Errorf("Keys() = %v after Reconcile, want %v", keys, tt.items)
+			}
+		})
+	}
+}
+
+func TestNamespaceScoped(t *testing.T) {
+	tests := []struct {
+		version int
+		profile string
+		backend keyring.BackendType
+		want    bool
+	}{
+		{1, "", keyring.PassBackend, false},
+		{1, "", keyring.KWalletBackend, false},
+		{1, "", keyring.SecretServiceBackend, true},
+		{1, "", keyring.KeychainBackend, true},
+		{1, "", keyring.FileBackend, true},
+		{1, "client", keyring.PassBackend, true},
+	}
+	for _, tt := range tests {
+		ns, err := namespaceFor(tt.version, tt.profile, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ns.scoped(string(tt.backend)); got != tt.want {
+			t.Errorf("v%d profile %q %s: scoped = %v, want %v", tt.version, tt.profile, tt.backend, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package keyring
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/99designs/keyring"
+)
+
+// timeoutKeyring bounds every backend call, so a hung service (e.g. a D-Bus
+// Secret Service that never answers) fails instead of freezing gham.
+type timeoutKeyring struct {
+	inner   keyring.Keyring
+	timeout time.Duration
+}
+
+// withTimeout runs fn and gives up after timeout. A call that times out keeps
+// running in the background; gham is a short-lived process, so it is simply abandoned.
+func withTimeout(timeout time.Duration, op string, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("keyring %s timed out after %s (set %s to change the limit)", op, timeout, EnvTimeout)
+	}
+}
+
+func (t *timeoutKeyring) Get(key string) (keyring.Item, error) {
+	var item keyring.Item
+	err := withTimeout(t.timeout, "get", func() error {
+		var err error
+		item, err = t.inner.Get(key)
+		return err
+	})
+	return item, err
+}
+
+func (t *timeoutKeyring) GetMetadata(key string) (keyring.Metadata, error) {
+	var md keyring.Metadata
+	err := withTimeout(t.timeout, "metadata lookup", func() error {
+		var err error
+		md, err = t.inner.GetMetadata(key)
+		return err
+	})
+	return md, err
+}
+
+func (t *timeoutKeyring) Set(item keyring.Item) error {
+	return withTimeout(t.timeout, "set", func() error { return t.inner.Set(item) })
+}
+
+func (t *timeoutKeyring) Remove(key string) error {
+	return withTimeout(t.timeout, "remove", func() error { return t.inner.Remove(key) })
+}
+
+func (t *timeoutKeyring) Keys() ([]string, error) {
+	var keys []string
+	err := withTimeout(t.timeout, "list", func() error {
+		var err error
+		keys, err = t.inner.Keys()
+		return err
+	})
+	return keys, err
+}
@@ -0,0 +1,148 @@
+package keyring
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/99designs/keyring"
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// CurrentVersion is the keyring item format written by this build.
+//
+// Each version lives in its own namespace (service name, Secret Service
+// collection, pass/WinCred prefix, file directory), so a new item format can be
+// adopted by copying tokens across with 'gham keyring migrate --from-version'
+// instead of users re-entering every token.
+const CurrentVersion = 1
+
+// itemCodec converts between a token and the bytes stored in a keyring item.
+type itemCodec struct {
+	encode func(token string) ([]byte, error)
+	decode func(data []byte) (string, error)
+}
+
+// itemCodecs holds every item format this build can read and write.
+var itemCodecs = map[int]itemCodec{
+	// v1: the raw token bytes.
+	1: {
+		encode: func(token string) ([]byte, error) { return []byte(token), nil },
+		decode: func(data []byte) (string, error) { return string(data), nil },
+	},
+}
+
+// namespace is where items of one format version are kept in each backend.
+type namespace struct {
+	service       string // keychain, KWallet, keyctl, WinCred
+	collection    string // Secret Service
+	prefix        string // pass, WinCred
+	kwalletFolder string // KWallet; empty means the backend's shared default folder
+	fileDir       string // file backend, relative to the config dir
+}
+
+// ServiceName returns the keyring service name for an item format version.
+func ServiceName(version int) string {
+	if version == 1 {
+		return config.KeyringService
+	}
+	return fmt.Sprintf("%s%d", strings.TrimSuffix(config.KeyringService, "1"), version)
+}
+
+// SupportedVersions lists the item format versions this build understands.
+func SupportedVersions() []int {
+	versions := make([]int, 0, len(itemCodecs))
+	for v := 1; v <= CurrentVersion; v++ {
+		if _, ok := itemCodecs[v]; ok {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// namespaceFor returns the namespace for an item format version in a config
+// profile. Each named profile gets its own namespace, so its tokens never mix
+// with those of the default profile, and so does each config file given with
+// --config or GHAM_CONFIG (configID, see config.Store.ConfigID).
+func namespaceFor(version int, profile, configID string) (namespace, error) {
+	if _, ok := itemCodecs[version]; !ok {
+		return namespace{}, fmt.Errorf("unsupported keyring item version %d (supported: %v)", version, SupportedVersions())
+	}
+	var ns namespace
+	if version == 1 {
+		// v1 predates versioned namespaces; keep reading items where they were written.
+		ns = namespace{
+			service:    ServiceName(1),
+			collection: "gham",
+			fileDir:    fileKeyringDirName,
+		}
+	} else {
+		suffix := fmt.Sprintf("gham-v%d", version)
+		ns = namespace{
+			service:       ServiceName(version),
+			collection:    suffix,
+			prefix:        suffix,
+			kwalletFolder: suffix,
+			fileDir:       fmt.Sprintf("%s-v%d", fileKeyringDirName, version),
+		}
+	}
+	if configID != "" {
+		// The config file decides which tokens are meant, whatever the profile.
+		ns.service += "_config_" + configID
+		ns.collection += "-config-" + configID
+		if ns.prefix == "" {
+			ns.prefix = "gham"
+		}
+		ns.prefix += "-config-" + configID
+		if ns.kwalletFolder == "" {
+			ns.kwalletFolder = "gham"
+		}
+		ns.kwalletFolder += "-config-" + configID
+		ns.fileDir = filepath.Join("configs", configID, ns.fileDir)
+		return ns, nil
+	}
+	if profile == "" {
+		return ns, nil
+	}
+	ns.service += "_profile_" + profile
+	ns.collection += "-profile-" + profile
+	if ns.prefix == "" {
+		ns.prefix = "gham"
+	}
+	ns.prefix += "-profile-" + profile
+	if ns.kwalletFolder != "" {
+		ns.kwalletFolder += "-profile-" + profile
+	}
+	ns.fileDir = filepath.Join("profiles", profile, ns.fileDir)
+	return ns, nil
+}
+
+// scoped reports whether the backend's Keys lists only items in this
+// namespace. v1 pass items live at the top of the password store and v1
+// KWallet items in its shared default folder, so there Keys also lists the
+// items of other applications.
+func (ns namespace) scoped(backend string) bool {
+	switch keyring.BackendType(backend) {
+	case keyring.PassBackend:
+		return ns.prefix != ""
+	case keyring.KWalletBackend:
+		return ns.kwalletFolder != ""
+	}
+	return true
+}
+
+func encodeItem(version int, token string) ([]byte, error) {
+	codec, ok := itemCodecs[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported keyring item version %d", version)
+	}
+	return codec.encode(token)
+}
+
+func decodeItem(version int, data []byte) (string, error) {
+	codec, ok := itemCodecs[version]
+	if !ok {
+		return "", fmt.Errorf("unsupported keyring item version %d", version)
+	}
+	return codec.decode(data)
+}
@@ -0,0 +1,100 @@
+package keyring
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/keyring"
+)
+
+func TestNamespacesAreDistinct(t *testing.T) {
+	scopes := []struct{ profile, configID string }{
+		{"", ""},
+		{"client", ""},
+		{"", "0123456789ab"},
+		{"", "ba9876543210"},
+	}
+	for _, version := range SupportedVersions() {
+		seen := map[string]string{}
+		for _, sc := range scopes {
+			ns, err := namespaceFor(version, sc.profile, sc.configID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			label := sc.profile + "/" + sc.configID
+			for kind, value := range map[string]string{
+				"service":    ns.service,
+				"collection": ns.collection,
+				"fileDir":    ns.fileDir,
+			} {
+				key := kind + "=" + value
+				if other, dup := seen[key]; dup {
+					t.Errorf("v%d: %s and %s share %s", version, other, label, key)
+				}
+				seen[key] = label
+			}
+			if sc.configID != "" {
+				for _, backend := range []keyring.BackendType{keyring.PassBackend, keyring.KWalletBackend} {
+					if !ns.scoped(string(backend)) {
+						t.Errorf("v%d config %s: %s namespace is not scoped", version, sc.configID, backend)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNamespaceFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int
+		profile  string
+		configID string
+		want     namespace
+	}{
+		{
+			// Where v1 items have always been written; moving them would lose tokens.
+			name:    "v1 default",
+			version: 1,
+			want:    namespace{service: "GHAM_PAT_Storage_v1", collection: "gham", fileDir: fileKeyringDirName},
+		},
+		{
+			name:    "v1 profile",
+			version: 1,
+			profile: "client",
+			want: namespace{
+				service:    "GHAM_PAT_Storage_v1_profile_client",
+				collection: "gham-profile-client",
+				prefix:     "gham-profile-client",
+				fileDir:    filepath.Join("profiles", "client", fileKeyringDirName),
+			},
+		},
+		{
+			name:     "v1 config file wins over the profile",
+			version:  1,
+			profile:  "client",
+			configID: "abc123",
+			want: namespace{
+				service:       "GHAM_PAT_Storage_v1_config_abc123",
+				collection:    "gham-config-abc123",
+				prefix:        "gham-config-abc123",
+				kwalletFolder: "gham-config-abc123",
+				fileDir:       filepath.Join("configs", "abc123", fileKeyringDirName),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := namespaceFor(tt.version, tt.profile, tt.configID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("namespaceFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := namespaceFor(CurrentVersion+1, "", ""); err == nil {
+		t.Error("namespaceFor() accepted an unsupported version")
+	}
+}
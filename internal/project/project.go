@@ -0,0 +1,224 @@
+// Package project reads a repository's committed .gham.yaml, which declares
+// what the context used to work on it must satisfy, and checks contexts
+// against it.
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the project file, committed at the root of the repository.
+const FileName = ".gham.yaml"
+
+// Enforcement modes.
+const (
+	EnforceWarn   = "warn"
+	EnforceRefuse = "refuse"
+)
+
+// Requirements is the content of a project file.
+type Requirements struct {
+	Host        string   `yaml:"host,omitempty"`        // e.g. "github.com"; the origin remote must be on it
+	Owner       string   `yaml:"owner,omitempty"`       // the origin remote must belong to this user or organization
+	EmailDomain string   `yaml:"emailDomain,omitempty"` // e.g. "client.com"; the context's email must use it
+	SignCommits bool     `yaml:"signCommits,omitempty"` // commit.gpgsign must be enabled
+	TokenScopes []string `yaml:"tokenScopes,omitempty"` // classic token scopes, e.g. ["repo", "read:org"]
+	Enforce     string   `yaml:"enforce,omitempty"`     // "warn" (default) or "refuse"
+
+	path string
+}
+
+// Path returns the file the requirements were read from.
+func (r *Requirements) Path() string { return r.path }
+
+// Refuse reports whether violations must stop the command.
+func (r *Requirements) Refuse() bool { return r.Enforce == EnforceRefuse }
+
+// Load reads the project file at the top of a working tree. It returns nil if
+// there is none.
+func Load(workTree string) (*Requirements, error) {
+	if workTree == "" {
+		return nil, nil // Bare repositories have no committed files to read.
+	}
+	path := filepath.Join(workTree, FileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	r := &Requirements{path: path}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(r); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	switch r.Enforce {
+	case "":
+		r.Enforce = EnforceWarn
+	case EnforceWarn, EnforceRefuse:
+	default:
+		return nil, fmt.Errorf("%s: enforce must be '%s' or '%s', not '%s'", path, EnforceWarn, EnforceRefuse, r.Enforce)
+	}
+	r.Host = strings.ToLower(r.Host)
+	r.Owner = strings.ToLower(r.Owner)
+	r.EmailDomain = strings.ToLower(strings.TrimPrefix(r.EmailDomain, "@"))
+	return r, nil
+}
+
+// Violation is a requirement the context or checkout does not meet.
+type Violation struct {
+	Message string
+	// Unverified is set when the requirement could not be checked (e.g. the
+	// scopes of a fine-grained token); such findings never refuse a command.
+	Unverified bool
+}
+
+// ContextViolations checks only the requirements that depend on the context
+// itself, which is what 'gham repo assign' can check up front.
+func (r *Requirements) ContextViolations(ctx *config.Context) []Violation {
+	if msg := r.checkEmail(ctx); msg != "" {
+		return []Violation{{Message: msg}}
+	}
+	return nil
+}
+
+// Checkout is what Check needs to know about the repository.
+type Checkout struct {
+	Remote       string // origin identity, see config.NormalizeRemote
+	SignsCommits bool   // commit.gpgsign is enabled
+	// TokenHosts are the hosts the context's token is meant for (see
+	// config.TokenHosts). Its scopes are only looked up on one of them.
+	TokenHosts []string
+}
+
+// Check compares the active context (nil if none) and the checkout against
+// the requirements. The token's scopes are only checked when token is set,
+// since that takes a request to the host's API.
+func (r *Requirements) Check(ctx *config.Context, checkout Checkout, token string) []Violation {
+	var v []Violation
+	if ctx == nil {
+		return []Violation{{Message: "no GHAM context is active, but this project declares requirements for it"}}
+	}
+	v = append(v, r.ContextViolations(ctx)...)
+	if (r.Host != "" || r.Owner != "") && checkout.Remote == "" {
+		v = append(v, Violation{Message: "the repository has no origin remote to check against the required host and owner"})
+	} else if r.Host != "" || r.Owner != "" {
+		remote := checkout.Remote
+		host, owner := "", ""
+		if parts := strings.SplitN(remote, "/", 3); len(parts) == 3 {
+			host, owner = parts[0], parts[1]
+		}
+		if r.Host != "" && host != r.Host {
+			v = append(v, Violation{Message: fmt.Sprintf("origin remote '%s' is not on the required host '%s'", remote, r.Host)})
+		}
+		if r.Owner != "" && owner != r.Owner {
+			v = append(v, Violation{Message: fmt.Sprintf("origin remote '%s' does not belong to the required owner '%s'", remote, r.Owner)})
+		}
+	}
+	if r.SignCommits && !checkout.SignsCommits {
+		v = append(v, Violation{Message: "commits must be signed, but commit.gpgsign is not enabled"})
+	}
+	if len(r.TokenScopes) > 0 && token != "" {
+		v = append(v, r.checkScopes(ctx, checkout, token)...)
+	}
+	return v
+}
+
+func (r *Requirements) checkEmail(ctx *config.Context) string {
+	if r.EmailDomain == "" {
+		return ""
+	}
+	if !strings.HasSuffix(strings.ToLower(ctx.Email), "@"+r.EmailDomain) {
+		return fmt.Sprintf("context '%s' uses email '%s', but this project requires an @%s address", ctx.Name, ctx.Email, r.EmailDomain)
+	}
+	return ""
+}
+
+// apiClient is used to look up a token's scopes.
+var apiClient = &http.Client{Timeout: 10 * time.Second}
+
+// checkScopes asks the API of the origin's host which scopes the token has.
+// GitHub reports them in the X-OAuth-Scopes header for classic tokens only.
+// The host is never taken from the project file, which the repository
+// controls, and the token is only sent to a host it is meant for.
+func (r *Requirements) checkScopes(ctx *config.Context, checkout Checkout, token string) []Violation {
+	host, _, _ := strings.Cut(checkout.Remote, "/")
+	if host == "" {
+		return []Violation{{Message: "token scopes were not checked: the repository has no origin remote", Unverified: true}}
+	}
+	if !slices.Contains(checkout.TokenHosts, host) {
+		return []Violation{{Message: fmt.Sprintf("token scopes were not checked: the token of context '%s' is not meant for '%s'", ctx.Name, host), Unverified: true}}
+	}
+	api := "https://" + host + "/api/v3/"
+	if host == "github.com" {
+		api = "https://api.github.com/"
+	}
+	req, err := http.NewRequest(http.MethodGet, api, nil)
+	if err != nil {
+		return []Violation{{Message: fmt.Sprintf("could not check token scopes: %v", err), Unverified: true}}
+	}
+	req.Header.Set("Authorization", "token "+token)
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return []Violation{{Message: fmt.Sprintf("could not check token scopes: %v", err), Unverified: true}}
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return []Violation{{Message: fmt.Sprintf("could not check token scopes: %s answered %s", host, resp.Status), Unverified: true}}
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return []Violation{{Message: fmt.Sprintf("the token of context '%s' does not report scopes (fine-grained token?); required: %s", ctx.Name, strings.Join(r.TokenScopes, ", ")), Unverified: true}}
+	}
+	granted := map[string]bool{}
+	for _, s := range strings.Split(strings.Join(header, ","), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			granted[s] = true
+		}
+	}
+	var v []Violation
+	for _, want := range r.TokenScopes {
+		if !hasScope(granted, want) {
+			v = append(v, Violation{Message: fmt.Sprintf("the token of context '%s' lacks the required scope '%s'", ctx.Name, want)})
+		}
+	}
+	return v
+}
+
+// hasScope reports whether want is granted directly or through a broader
+// scope: "repo" covers "repo:status" and "public_repo", "admin:org" covers
+// "write:org" and "read:org", "write:packages" covers "read:packages".
+func hasScope(granted map[string]bool, want string) bool {
+	if granted[want] {
+		return true
+	}
+	if parent, _, ok := strings.Cut(want, ":"); ok && granted[parent] {
+		return true
+	}
+	if want == "public_repo" && granted["repo"] {
+		return true
+	}
+	if level, resource, ok := strings.Cut(want, ":"); ok {
+		switch level {
+		case "read":
+			return granted["write:"+resource] || granted["admin:"+resource]
+		case "write":
+			return granted["admin:"+resource]
+		}
+	}
+	return false
+}
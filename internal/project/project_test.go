@@ -0,0 +1,208 @@
+package project
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// recordingTransport sends every request to srv instead of its real host and
+// records the hosts that were asked for.
+type recordingTransport struct {
+	srv   *httptest.Server
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.hosts = append(t.hosts, req.URL.Host)
+	t.mu.Unlock()
+	target, _ := url.Parse(t.srv.URL)
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCheckScopesNeverUsesProjectHost(t *testing.T) {
+	var attackerHits int
+	var mu sync.Mutex
+	attacker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attackerHits++
+		mu.Unlock()
+	}))
+	defer attacker.Close()
+	attackerHost := strings.TrimPrefix(attacker.URL, "http://")
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name       string
+		remote     string
+		tokenHosts []string
+		wantHosts  []string // hosts asked for
+		wantUnver  bool     // the scope check is reported as unverified
+	}{
+		{"github origin", "github.com/o/r", []string{"github.com"}, []string{"api.github.com"}, false},
+		{"origin on a host the token is not for", attackerHost + "/o/r", []string{"github.com"}, nil, true},
+		{"no origin", "", []string{"github.com"}, nil, true},
+		{"enterprise origin", "ghe.example.com/o/r", []string{"ghe.example.com"}, []string{"ghe.example.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &recordingTransport{srv: api}
+			saved := apiClient
+			apiClient = &http.Client{Transport: transport}
+			defer func() { apiClient = saved }()
+
+			req := &Requirements{Host: attackerHost, TokenScopes: []string{"repo"}, Enforce: EnforceWarn}
+			ctx := &config.Context{Name: "work", Email: "me@example.com"}
+			violations := req.checkScopes(ctx, Checkout{Remote: tt.remote, TokenHosts: tt.tokenHosts}, "secret")
+
+			if strings.Join(transport.hosts, ",") != strings.Join(tt.wantHosts, ",") {
+				t.Errorf("requested hosts = %v, want %v", transport.hosts, tt.wantHosts)
+			}
+			unverified := len(violations) == 1 && violations[0].Unverified
+			if unverified != tt.wantUnver {
+				t.Errorf("violations = %+v, want unverified = %v", violations, tt.wantUnver)
+			}
+			if !tt.wantUnver && len(violations) != 0 {
+				t.Errorf("violations = %+v, want none", violations)
+			}
+		})
+	}
+	if attackerHits != 0 {
+		t.Errorf("the host named in the project file received %d request(s)", attackerHits)
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	granted := map[string]bool{"repo": true, "admin:org": true, "write:packages": true}
+	tests := []struct {
+		want string
+		ok   bool
+	}{
+		{"repo", true},
+		{"repo:status", true},
+		{"public_repo", true},
+		{"read:org", true},
+		{"write:org", true},
+		{"read:packages", true},
+		{"delete:packages", false},
+		{"workflow", false},
+	}
+	for _, tt := range tests {
+		if got := hasScope(granted, tt.want); got != tt.ok {
+			t.Errorf("hasScope(%q) = %v, want %v", tt.want, got, tt.ok)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // "" for no project file
+		want    *Requirements
+		wantErr string
+	}{
+		{name: "no file"},
+		{
+			name:    "defaults and normalization",
+			content: "host: GitHub.com\nowner: Acme\nemailDomain: '@Client.com'\n",
+			want:    &Requirements{Host: "github.com", Owner: "acme", EmailDomain: "client.com", Enforce: EnforceWarn},
+		},
+		{
+			name:    "everything",
+			content: "signCommits: true\ntokenScopes: [repo, read:org]\nenforce: refuse\n",
+			want:    &Requirements{SignCommits: true, TokenScopes: []string{"repo", "read:org"}, Enforce: EnforceRefuse},
+		},
+		{name: "empty file", content: "\n", want: &Requirements{Enforce: EnforceWarn}},
+		{name: "misspelled key", content: "emailDomian: client.com\n", wantErr: "emailDomian"},
+		{name: "unknown enforcement", content: "enforce: block\n", wantErr: "enforce must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.content != "" {
+				if err := os.WriteFile(filepath.Join(dir, FileName), []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Load(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil {
+				tt.want.path = filepath.Join(dir, FileName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if r, err := Load(""); r != nil || err != nil {
+		t.Errorf("Load() of a bare repository = %+v, %v, want nothing", r, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	work := &config.Context{Name: "work", Email: "me@Client.com"}
+	personal := &config.Context{Name: "personal", Email: "me@home.example"}
+	req := &Requirements{Host: "github.com", Owner: "acme", EmailDomain: "client.com", SignCommits: true, Enforce: EnforceRefuse}
+
+	tests := []struct {
+		name     string
+		ctx      *config.Context
+		checkout Checkout
+		want     []string // substrings of the violations, in order
+	}{
+		{"all met", work, Checkout{Remote: "github.com/acme/api", SignsCommits: true}, nil},
+		{"no context", nil, Checkout{Remote: "github.com/acme/api", SignsCommits: true}, []string{"no GHAM context"}},
+		{"wrong email", personal, Checkout{Remote: "github.com/acme/api", SignsCommits: true}, []string{"@client.com address"}},
+		{"wrong host and owner", work, Checkout{Remote: "gitlab.com/other/api", SignsCommits: true}, []string{"required host", "required owner"}},
+		{"no origin", work, Checkout{SignsCommits: true}, []string{"no origin remote"}},
+		{"unsigned", work, Checkout{Remote: "github.com/acme/api"}, []string{"must be signed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := req.Check(tt.ctx, tt.checkout, "")
+			if len(violations) != len(tt.want) {
+				t.Fatalf("Check() = %+v, want %d violation(s)", violations, len(tt.want))
+			}
+			for i, v := range violations {
+				if !strings.Contains(v.Message, tt.want[i]) || v.Unverified {
+					t.Errorf("violation %d = %+v, want a verified one mentioning %q", i, v, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if v := req.ContextViolations(personal); len(v) != 1 {
+		t.Errorf("ContextViolations() = %+v, want the email violation", v)
+	}
+	if v := (&Requirements{TokenScopes: []string{"repo"}}).Check(work, Checkout{}, ""); len(v) != 0 {
+		t.Errorf("Check() without a token = %+v, want the scopes left unchecked", v)
+	}
+}
@@ -0,0 +1,93 @@
+// Package tokensource resolves a context's token from wherever the context
+// declares it lives: gham's keyring, an environment variable, a command, or
+// HashiCorp Vault.
+package tokensource
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+	"github.com/riad804/github-auth-manager/internal/keyring"
+	"github.com/riad804/github-auth-manager/internal/utils"
+)
+
+// Source retrieves the token for one context.
+type Source interface {
+	// Token returns the secret, or an error if it cannot be retrieved.
+	Token() (string, error)
+	// String describes the source for display without revealing the secret.
+	String() string
+}
+
+// For returns the token source declared by ctx.
+func For(ctx *config.Context) (Source, error) {
+	if err := config.Current().Config.Policies.CheckTokenSource(ctx); err != nil {
+		return nil, err
+	}
+	switch ctx.TokenSourceType() {
+	case config.TokenSourceKeyring:
+		return keyringSource{contextName: ctx.Name}, nil
+	case config.TokenSourceEnv:
+		name := strings.TrimSpace(ctx.TokenSource.Env)
+		if name == "" {
+			return nil, fmt.Errorf("context '%s' uses an env token source but no variable name is set", ctx.Name)
+		}
+		return envSource{name: name}, nil
+	case config.TokenSourceCommand:
+		command := strings.TrimSpace(ctx.TokenSource.Command)
+		if command == "" {
+			return nil, fmt.Errorf("context '%s' uses a command token source but no command is set", ctx.Name)
+		}
+		return commandSource{command: command}, nil
+	case config.TokenSourceVault:
+		return newVaultSource(ctx)
+	default:
+		return nil, fmt.Errorf("context '%s' has unknown token source type '%s'", ctx.Name, ctx.TokenSource.Type)
+	}
+}
+
+// Resolve returns the token for ctx from its declared source.
+func Resolve(ctx *config.Context) (string, error) {
+	src, err := For(ctx)
+	if err != nil {
+		return "", err
+	}
+	token, err := src.Token()
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("token source %s returned an empty token for context '%s'", src, ctx.Name)
+	}
+	return token, nil
+}
+
+type keyringSource struct {
+	contextName string
+}
+
+func (s keyringSource) Token() (string, error) { return keyring.GetToken(s.contextName) }
+func (s keyringSource) String() string         { return "keyring" }
+
+type envSource struct {
+	name string
+}
+
+func (s envSource) Token() (string, error) {
+	token, ok := os.LookupEnv(s.name)
+	if !ok {
+		return "", fmt.Errorf("environment variable '%s' is not set", s.name)
+	}
+	return strings.TrimSpace(token), nil
+}
+
+func (s envSource) String() string { return "env:" + s.name }
+
+type commandSource struct {
+	command string
+}
+
+func (s commandSource) Token() (string, error) { return utils.RunShellCommand(s.command) }
+func (s commandSource) String() string         { return "command:" + s.command }
@@ -0,0 +1,94 @@
+package tokensource
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+func TestResolve(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the token commands use sh")
+	}
+	t.Setenv("GHAM_TEST_TOKEN", " ghp_env\n")
+	t.Setenv("GHAM_TEST_EMPTY", "")
+	source := func(typ, value string) *config.TokenSource {
+		ts := &config.TokenSource{Type: typ}
+		switch typ {
+		case config.TokenSourceEnv:
+			ts.Env = value
+		case config.TokenSourceCommand:
+			ts.Command = value
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name     string
+		source   *config.TokenSource
+		allowed  []string // policies.allowedTokenSources
+		want     string
+		wantErr  string
+		wantDesc string
+	}{
+		{name: "env", source: source(config.TokenSourceEnv, "GHAM_TEST_TOKEN"), want: "ghp_env", wantDesc: "env:GHAM_TEST_TOKEN"},
+		{name: "env unset", source: source(config.TokenSourceEnv, "GHAM_TEST_UNSET"), wantErr: "is not set"},
+		{name: "env empty", source: source(config.TokenSourceEnv, "GHAM_TEST_EMPTY"), wantErr: "empty token"},
+		{name: "env without a name", source: source(config.TokenSourceEnv, " "), wantErr: "no variable name"},
+		{name: "command", source: source(config.TokenSourceCommand, "printf 'ghp_cmd\\n'"), want: "ghp_cmd", wantDesc: "command:printf 'ghp_cmd\\n'"},
+		{name: "command fails", source: source(config.TokenSourceCommand, "exit 1"), wantErr: "failed"},
+		{name: "command prints nothing", source: source(config.TokenSourceCommand, "true"), wantErr: "empty token"},
+		{name: "command without a command", source: source(config.TokenSourceCommand, ""), wantErr: "no command"},
+		{name: "unknown type", source: source("ldap", ""), wantErr: "unknown token source type"},
+		{
+			name:    "forbidden by policy",
+			source:  source(config.TokenSourceCommand, "printf ghp_cmd"),
+			allowed: []string{config.TokenSourceKeyring, config.TokenSourceEnv},
+			wantErr: "not allowed by policy",
+		},
+		{
+			name:     "allowed by policy",
+			source:   source(config.TokenSourceEnv, "GHAM_TEST_TOKEN"),
+			allowed:  []string{config.TokenSourceEnv},
+			want:     "ghp_env",
+			wantDesc: "env:GHAM_TEST_TOKEN",
+		},
+	}
+	saved := config.Current()
+	t.Cleanup(func() { config.SetCurrent(saved) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := config.NewStore("", "")
+			store.Config.Policies.AllowedTokenSources = tt.allowed
+			config.SetCurrent(store)
+			ctx := &config.Context{Name: "work", TokenSource: tt.source}
+
+			got, err := Resolve(ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() = %q, %v, want an error mentioning %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve() = %q, %v, want %q", got, err, tt.want)
+			}
+			if src, _ := For(ctx); src.String() != tt.wantDesc || src.String() != ctx.TokenSourceString() {
+				t.Errorf("String() = %q, want %q like TokenSourceString()", src.String(), tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestForKeyring(t *testing.T) {
+	saved := config.Current()
+	t.Cleanup(func() { config.SetCurrent(saved) })
+	config.SetCurrent(config.NewStore("", ""))
+
+	src, err := For(&config.Context{Name: "work"})
+	if err != nil || src.String() != "keyring" {
+		t.Errorf("For() of a context without a token source = %v, %v, want the keyring", src, err)
+	}
+}
@@ -0,0 +1,413 @@
+package tokensource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// vaultHTTPClient is used for all Vault requests.
+var vaultHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// vaultSource reads a token from HashiCorp Vault.
+type vaultSource struct {
+	contextName string
+	cfg         config.VaultSource
+	address     string
+	client      *http.Client
+}
+
+func newVaultSource(ctx *config.Context) (*vaultSource, error) {
+	if ctx.TokenSource.Vault == nil {
+		return nil, fmt.Errorf("context '%s' uses a vault token source but has no 'vault' settings", ctx.Name)
+	}
+	cfg := *ctx.TokenSource.Vault
+	if cfg.Engine == "" {
+		cfg.Engine = config.VaultEngineKV2
+	}
+	switch cfg.Engine {
+	case config.VaultEngineKV2:
+		if cfg.Mount == "" {
+			cfg.Mount = "secret"
+		}
+		if cfg.Field == "" {
+			cfg.Field = "token"
+		}
+		if strings.Trim(cfg.Path, "/") == "" {
+			return nil, fmt.Errorf("context '%s' uses a vault kv2 token source but no path is set", ctx.Name)
+		}
+	case config.VaultEngineGitHub:
+		if cfg.Mount == "" {
+			cfg.Mount = "github"
+		}
+		if cfg.Path == "" {
+			cfg.Path = "token"
+		}
+	default:
+		return nil, fmt.Errorf("context '%s' has unknown vault engine '%s' (expected '%s' or '%s')", ctx.Name, cfg.Engine, config.VaultEngineKV2, config.VaultEngineGitHub)
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	address := strings.TrimRight(cfg.Address, "/")
+	if address == "" {
+		address = strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("context '%s' uses a vault token source but no address is set (configure 'address' or VAULT_ADDR)", ctx.Name)
+	}
+	return &vaultSource{contextName: ctx.Name, cfg: cfg, address: address, client: vaultHTTPClient}, nil
+}
+
+func (s *vaultSource) String() string {
+	return fmt.Sprintf("vault:%s/%s", s.cfg.Mount, strings.Trim(s.cfg.Path, "/"))
+}
+
+// Token returns a cached secret if its lease is still valid, otherwise reads a fresh one.
+func (s *vaultSource) Token() (string, error) {
+	cacheKey := s.cacheKey()
+	if token, ok := vaultCache.get(cacheKey); ok {
+		return token, nil
+	}
+
+	vaultToken, err := s.login()
+	if err != nil {
+		return "", err
+	}
+
+	var token string
+	var lease time.Duration
+	switch s.cfg.Engine {
+	case config.VaultEngineGitHub:
+		token, lease, err = s.readGitHubToken(vaultToken)
+	default:
+		token, lease, err = s.readKV2(vaultToken)
+	}
+	if err != nil {
+		return "", err
+	}
+	vaultCache.put(cacheKey, token, lease)
+	return token, nil
+}
+
+// cacheKey identifies the secret, so contexts sharing a Vault path share a lease.
+func (s *vaultSource) cacheKey() string {
+	return strings.Join([]string{s.address, s.cfg.Namespace, s.cfg.Engine, s.cfg.Mount, strings.Trim(s.cfg.Path, "/"), s.cfg.Field}, "|")
+}
+
+// vaultResponse is the subset of Vault's response envelope gham reads.
+type vaultResponse struct {
+	LeaseDuration int             `json:"lease_duration"`
+	Data          json.RawMessage `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func (s *vaultSource) do(method, apiPath, vaultToken string, body any) (*vaultResponse, error) {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, s.address+"/v1/"+strings.TrimLeft(apiPath, "/"), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault request: %w", err)
+	}
+	if vaultToken != "" {
+		req.Header.Set("X-Vault-Token", vaultToken)
+	}
+	if s.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request to %s failed: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	var out vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode vault response from %s: %w", req.URL.Redacted(), err)
+	}
+	if resp.StatusCode/100 != 2 {
+		detail := strings.Join(out.Errors, "; ")
+		if detail == "" {
+			detail = resp.Status
+		}
+		return nil, fmt.Errorf("vault returned %d for %s: %s", resp.StatusCode, req.URL.Redacted(), detail)
+	}
+	return &out, nil
+}
+
+// login returns a Vault client token for the configured auth method.
+func (s *vaultSource) login() (string, error) {
+	auth := s.cfg.Auth
+	switch auth.Method {
+	case "", config.VaultAuthToken:
+		if token := strings.TrimSpace(os.Getenv("VAULT_TOKEN")); token != "" && auth.TokenFile == "" {
+			return token, nil
+		}
+		tokenFile := auth.TokenFile
+		if tokenFile == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to locate ~/.vault-token: %w", err)
+			}
+			tokenFile = filepath.Join(home, ".vault-token")
+		}
+		return readSecretFile(tokenFile, "vault token")
+	case config.VaultAuthAppRole:
+		if auth.RoleID == "" {
+			return "", fmt.Errorf("vault approle auth for context '%s' needs a roleId", s.contextName)
+		}
+		secretID := strings.TrimSpace(os.Getenv("VAULT_SECRET_ID"))
+		if auth.SecretIDFile != "" {
+			var err error
+			if secretID, err = readSecretFile(auth.SecretIDFile, "vault secret ID"); err != nil {
+				return "", err
+			}
+		}
+		if secretID == "" {
+			return "", fmt.Errorf("vault approle auth for context '%s' needs a secretIdFile or VAULT_SECRET_ID", s.contextName)
+		}
+		mount := auth.Mount
+		if mount == "" {
+			mount = "approle"
+		}
+		// The secret ID is part of the key (hashed), so a lease is only
+		// reused by callers that could log in themselves.
+		secretSum := sha256.Sum256([]byte(secretID))
+		loginKey := strings.Join([]string{"approle", s.address, s.cfg.Namespace, strings.Trim(mount, "/"), auth.RoleID, hex.EncodeToString(secretSum[:])}, "|")
+		if token, ok := vaultCache.get(loginKey); ok {
+			return token, nil
+		}
+		resp, err := s.do(http.MethodPost, "auth/"+strings.Trim(mount, "/")+"/login", "", map[string]string{
+			"role_id":   auth.RoleID,
+			"secret_id": secretID,
+		})
+		if err != nil {
+			return "", fmt.Errorf("vault approle login failed: %w", err)
+		}
+		if resp.Auth == nil || resp.Auth.ClientToken == "" {
+			return "", errors.New("vault approle login returned no client token")
+		}
+		vaultCache.put(loginKey, resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration)*time.Second)
+		return resp.Auth.ClientToken, nil
+	default:
+		return "", fmt.Errorf("context '%s' has unknown vault auth method '%s' (expected '%s' or '%s')", s.contextName, auth.Method, config.VaultAuthToken, config.VaultAuthAppRole)
+	}
+}
+
+// readKV2 reads a static token from a KV v2 secret. KV secrets carry no lease,
+// so they are only cached for the life of the process, never on disk.
+func (s *vaultSource) readKV2(vaultToken string) (string, time.Duration, error) {
+	apiPath := strings.Trim(s.cfg.Mount, "/") + "/data/" + strings.Trim(s.cfg.Path, "/")
+	resp, err := s.do(http.MethodGet, apiPath, vaultToken, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	var data struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return "", 0, fmt.Errorf("unexpected kv2 response for '%s': %w", apiPath, err)
+	}
+	value, ok := data.Data[s.cfg.Field].(string)
+	if !ok || value == "" {
+		return "", 0, fmt.Errorf("vault secret '%s' has no string field '%s'", apiPath, s.cfg.Field)
+	}
+	return value, 0, nil
+}
+
+// readGitHubToken requests a dynamic token from the GitHub secrets engine.
+func (s *vaultSource) readGitHubToken(vaultToken string) (string, time.Duration, error) {
+	apiPath := strings.Trim(s.cfg.Mount, "/") + "/" + strings.Trim(s.cfg.Path, "/")
+	resp, err := s.do(http.MethodGet, apiPath, vaultToken, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	var data struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return "", 0, fmt.Errorf("unexpected github engine response for '%s': %w", apiPath, err)
+	}
+	if data.Token == "" {
+		return "", 0, fmt.Errorf("vault github engine at '%s' returned no token", apiPath)
+	}
+	lease := time.Duration(resp.LeaseDuration) * time.Second
+	if !data.ExpiresAt.IsZero() {
+		if untilExpiry := time.Until(data.ExpiresAt); lease == 0 || untilExpiry < lease {
+			lease = untilExpiry
+		}
+	}
+	return data.Token, lease, nil
+}
+
+func readSecretFile(path, what string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from '%s': %w", what, path, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s file '%s' is empty", what, path)
+	}
+	return secret, nil
+}
+
+// leaseSafetyMargin is subtracted from every lease, so a token is never handed
+// to git moments before it expires.
+const leaseSafetyMargin = 30 * time.Second
+
+// leaseCacheFileName is the file under the user cache directory (e.g.
+// ~/.cache/gham) that keeps leased Vault secrets between gham invocations.
+const leaseCacheFileName = "vault-leases.json"
+
+// leaseCache holds Vault secrets for their lease duration. Leased secrets
+// (GitHub engine tokens, AppRole logins) are also written, with their expiry,
+// to a file only the user can read, so the next gham invocation reuses them
+// instead of logging in and minting a new token. Secrets without a lease (KV)
+// are kept for the current process only.
+type leaseCache struct {
+	mu      sync.Mutex
+	path    string // persistent cache file; "" keeps every secret in memory
+	loaded  bool
+	entries map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"` // zero: valid for this process only, never persisted
+}
+
+var vaultCache = newLeaseCache(defaultLeaseCachePath())
+
+func newLeaseCache(path string) *leaseCache {
+	return &leaseCache{path: path, entries: map[string]cachedSecret{}}
+}
+
+func defaultLeaseCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "" // No cache directory: leases live for the process only.
+	}
+	return filepath.Join(dir, config.AppName, leaseCacheFileName)
+}
+
+func (c *leaseCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		c.loaded = true
+		for k, entry := range c.readFile() {
+			if _, ok := c.entries[k]; !ok {
+				c.entries[k] = entry
+			}
+		}
+	}
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if entry.expired() {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.Token, true
+}
+
+func (c *leaseCache) put(key, token string, lease time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := cachedSecret{Token: token}
+	if lease > leaseSafetyMargin {
+		entry.ExpiresAt = time.Now().Add(lease - leaseSafetyMargin)
+	} else if lease > 0 {
+		return // Too close to expiry to be worth caching.
+	}
+	c.entries[key] = entry
+	if !entry.ExpiresAt.IsZero() {
+		// A cache that cannot be written only costs a new lease next time.
+		_ = c.writeFile(key, entry)
+	}
+}
+
+func (e cachedSecret) expired() bool {
+	return !e.ExpiresAt.IsZero() && !time.Now().Before(e.ExpiresAt)
+}
+
+// readFile returns the unexpired leases in the cache file. A missing or
+// unreadable file is an empty cache.
+func (c *leaseCache) readFile() map[string]cachedSecret {
+	leases := map[string]cachedSecret{}
+	if c.path == "" {
+		return leases
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil || json.Unmarshal(data, &leases) != nil {
+		return map[string]cachedSecret{}
+	}
+	for k, entry := range leases {
+		if entry.ExpiresAt.IsZero() || entry.expired() {
+			delete(leases, k)
+		}
+	}
+	return leases
+}
+
+// writeFile adds a lease to the cache file, keeping those other gham
+// processes wrote meanwhile and dropping expired ones. The file is replaced
+// atomically and is readable by the user only.
+func (c *leaseCache) writeFile(key string, entry cachedSecret) error {
+	if c.path == "" {
+		return nil
+	}
+	leases := c.readFile()
+	leases[key] = entry
+	data, err := json.Marshal(leases)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, leaseCacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed.
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
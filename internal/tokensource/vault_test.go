@@ -0,0 +1,283 @@
+package tokensource
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riad804/github-auth-manager/internal/config"
+)
+
+// fakeVault is a stand-in for the parts of the Vault API gham uses.
+type fakeVault struct {
+	t      *testing.T
+	mu     sync.Mutex
+	reads  map[string]int // request path -> count
+	lease  int            // lease_duration of github engine tokens, in seconds
+	serial int
+}
+
+func newFakeVault(t *testing.T, lease int) (*fakeVault, *httptest.Server) {
+	v := &fakeVault{t: t, reads: map[string]int{}, lease: lease}
+	srv := httptest.NewServer(v)
+	t.Cleanup(srv.Close)
+	return v, srv
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reads[r.URL.Path]++
+	reply := func(body any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var login map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		if login["role_id"] != "role" || login["secret_id"] != "secret-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			reply(map[string]any{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		reply(map[string]any{"auth": map[string]any{"client_token": "vault-token", "lease_duration": 3600}})
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != "vault-token" {
+		w.WriteHeader(http.StatusForbidden)
+		reply(map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/secret/data/github/work":
+		reply(map[string]any{"data": map[string]any{"data": map[string]any{"token": "ghp_static"}}})
+	case "/v1/github/token":
+		v.serial++
+		reply(map[string]any{
+			"lease_duration": v.lease,
+			"data":           map[string]any{"token": "ghs_dynamic" + strconv.Itoa(v.serial)},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]any{"errors": []string{"no handler for route"}})
+	}
+}
+
+func (v *fakeVault) count(path string) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.reads[path]
+}
+
+func vaultContext(address string, vault config.VaultSource) *config.Context {
+	vault.Address = address
+	return &config.Context{Name: "work", TokenSource: &config.TokenSource{Type: config.TokenSourceVault, Vault: &vault}}
+}
+
+// resetVaultCache gives the test an empty lease cache persisted to a
+// temporary file, and returns that file.
+func resetVaultCache(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), leaseCacheFileName)
+	saved := vaultCache
+	vaultCache = newLeaseCache(path)
+	t.Cleanup(func() { vaultCache = saved })
+	return path
+}
+
+func TestVaultSource(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_NAMESPACE", "")
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretIDFile, []byte("secret-id\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		vault     config.VaultSource
+		lease     int
+		path      string
+		want      string
+		wantReads int // reads of path after two Token calls
+	}{
+		{
+			name:      "kv2 is cached for the process",
+			vault:     config.VaultSource{Path: "github/work"},
+			path:      "/v1/secret/data/github/work",
+			want:      "ghp_static",
+			wantReads: 1,
+		},
+		{
+			name:      "github engine token is cached for its lease",
+			vault:     config.VaultSource{Engine: config.VaultEngineGitHub},
+			lease:     3600,
+			path:      "/v1/github/token",
+			want:      "ghs_dynamic1",
+			wantReads: 1,
+		},
+		{
+			name:      "github engine token about to expire is not cached",
+			vault:     config.VaultSource{Engine: config.VaultEngineGitHub},
+			lease:     int(leaseSafetyMargin / time.Second),
+			path:      "/v1/github/token",
+			want:      "ghs_dynamic1",
+			wantReads: 2,
+		},
+		{
+			name: "approle login",
+			vault: config.VaultSource{Path: "github/work", Auth: config.VaultAuth{
+				Method: config.VaultAuthAppRole, RoleID: "role", SecretIDFile: secretIDFile,
+			}},
+			path:      "/v1/secret/data/github/work",
+			want:      "ghp_static",
+			wantReads: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetVaultCache(t)
+			fake, srv := newFakeVault(t, tt.lease)
+			src, err := newVaultSource(vaultContext(srv.URL, tt.vault))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := src.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Token() = %q, want %q", got, tt.want)
+			}
+			if _, err := src.Token(); err != nil {
+				t.Fatal(err)
+			}
+			if n := fake.count(tt.path); n != tt.wantReads {
+				t.Errorf("%s read %d time(s), want %d", tt.path, n, tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestVaultSourceErrors(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "wrong-token")
+	resetVaultCache(t)
+	_, srv := newFakeVault(t, 0)
+	src, err := newVaultSource(vaultContext(srv.URL, config.VaultSource{Path: "github/work"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Token(); err == nil {
+		t.Error("Token() with a rejected Vault token succeeded")
+	}
+
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	src, err = newVaultSource(vaultContext(srv.URL, config.VaultSource{Path: "github/missing"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Token(); err == nil {
+		t.Error("Token() for a missing secret succeeded")
+	}
+}
+
+func TestLeaseCacheExpiry(t *testing.T) {
+	c := newLeaseCache("")
+	c.entries["old"] = cachedSecret{Token: "t", ExpiresAt: time.Now().Add(-time.Second)}
+	if _, ok := c.get("old"); ok {
+		t.Error("expired lease was returned")
+	}
+	if _, ok := c.entries["old"]; ok {
+		t.Error("expired lease was kept")
+	}
+}
+
+func TestVaultLeaseOutlivesTheProcess(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_NAMESPACE", "")
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretIDFile, []byte("secret-id"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	approle := config.VaultAuth{Method: config.VaultAuthAppRole, RoleID: "role", SecretIDFile: secretIDFile}
+
+	tests := []struct {
+		name      string
+		vault     config.VaultSource
+		wantReads map[string]int // requests after both invocations
+	}{
+		{
+			name:      "github engine token and login are reused",
+			vault:     config.VaultSource{Engine: config.VaultEngineGitHub, Auth: approle},
+			wantReads: map[string]int{"/v1/auth/approle/login": 1, "/v1/github/token": 1},
+		},
+		{
+			name:      "kv2 secret is read again, but without logging in",
+			vault:     config.VaultSource{Path: "github/work", Auth: approle},
+			wantReads: map[string]int{"/v1/auth/approle/login": 1, "/v1/secret/data/github/work": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := resetVaultCache(t)
+			fake, srv := newFakeVault(t, 3600)
+			var tokens []string
+			for invocation := 0; invocation < 2; invocation++ {
+				vaultCache = newLeaseCache(path) // A new gham process starts with an empty memory cache.
+				src, err := newVaultSource(vaultContext(srv.URL, tt.vault))
+				if err != nil {
+					t.Fatal(err)
+				}
+				token, err := src.Token()
+				if err != nil {
+					t.Fatal(err)
+				}
+				tokens = append(tokens, token)
+			}
+			if tokens[0] != tokens[1] {
+				t.Errorf("second invocation got %q, want the first one's %q", tokens[1], tokens[0])
+			}
+			for apiPath, want := range tt.wantReads {
+				if n := fake.count(apiPath); n != want {
+					t.Errorf("%s requested %d time(s), want %d", apiPath, n, want)
+				}
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("lease cache file mode = %o, want 600", perm)
+			}
+		})
+	}
+}
+
+func TestLeaseCacheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), leaseCacheFileName)
+	first := newLeaseCache(path)
+	first.put("leased", "ghs_a", time.Hour)
+	first.put("process-only", "ghp_b", 0)
+	first.put("expiring", "ghs_c", leaseSafetyMargin)
+
+	second := newLeaseCache(path)
+	for key, want := range map[string]bool{"leased": true, "process-only": false, "expiring": false} {
+		if _, ok := second.get(key); ok != want {
+			t.Errorf("%s found in a new process: %v, want %v", key, ok, want)
+		}
+	}
+
+	// Leases expire in the file too.
+	second.entries["leased"] = cachedSecret{Token: "ghs_a", ExpiresAt: time.Now().Add(-time.Second)}
+	if err := second.writeFile("leased", second.entries["leased"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newLeaseCache(path).get("leased"); ok {
+		t.Error("expired lease was read from the file")
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"syscall"
 
 	"golang.org/x/term"
 )
 
+// stdinReader is shared by all prompts, so input piped in for several prompts
+// is not lost to the buffer of an earlier one.
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // PromptForInput prompts the user for input with a given message.
 func PromptForInput(promptMessage string, sensitive bool) (string, error) {
 	fmt.Print(promptMessage)
@@ -23,10 +29,37 @@ func PromptForInput(promptMessage string, sensitive bool) (string, error) {
 		return strings.TrimSpace(string(bytePassword)), nil
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	input, err := stdinReader.ReadString('\n')
 	if err != nil {
 		return "", fmt.Errorf("failed to read input: %w", err)
 	}
 	return strings.TrimSpace(input), nil
 }
+
+// RunShellCommand runs commandLine through the platform shell and returns its
+// trimmed stdout. Stderr is passed through so the command can prompt or report errors.
+func RunShellCommand(commandLine string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", commandLine)
+	} else {
+		cmd = exec.Command("sh", "-c", commandLine)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("command '%s' failed: %w", commandLine, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Confirm asks a yes/no question and returns true only for an explicit yes.
+func Confirm(promptMessage string) (bool, error) {
+	answer, err := PromptForInput(promptMessage+" [y/N]: ", false)
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}